@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tomek7667/links/pkg/api"
+	"github.com/tomek7667/links/pkg/client"
+	"github.com/urfave/cli/v2"
+)
+
+func cmdAdd() *cli.Command {
+	return &cli.Command{
+		Name:      "add",
+		Usage:     "Save a link on a running linksserver",
+		ArgsUsage: "TITLE URL",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "server",
+				Usage:   "base url of the linksserver, including any --base-path",
+				EnvVars: []string{"LINKS_SERVER"},
+				Value:   "http://localhost",
+			},
+			&cli.StringFlag{
+				Name:  "unit",
+				Usage: "systemd unit serving the link",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 2 {
+				return fmt.Errorf("expected TITLE and URL, got %d arguments", c.NArg())
+			}
+			// SaveLink normalises again; this is for printing the stored url.
+			link, err := api.NormalizeLink(api.Link{
+				Title: c.Args().Get(0),
+				Url:   c.Args().Get(1),
+				Unit:  c.String("unit"),
+			})
+			if err != nil {
+				return err
+			}
+			cl, err := client.New(client.Options{BaseURL: c.String("server")})
+			if err != nil {
+				return err
+			}
+			if err := cl.SaveLink(c.Context, link); err != nil {
+				var apiErr *client.Error
+				if errors.As(err, &apiErr) && len(apiErr.Fields) > 0 {
+					return fmt.Errorf("%w (%s)", err, formatFieldErrors(apiErr.Fields))
+				}
+				return err
+			}
+			fmt.Printf("saved %s\n", link.Url)
+			return nil
+		},
+	}
+}
+
+func formatFieldErrors(fields []api.FieldError) string {
+	msgs := make([]string, len(fields))
+	for i, f := range fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tomek7667/links/internal/http"
+	"github.com/urfave/cli/v2"
+)
+
+func cmdAgent() *cli.Command {
+	return &cli.Command{
+		Name:  "agent",
+		Usage: "Collect resources only and push them to a central linksserver",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:     "server",
+				Usage:    "base url of the central linksserver (e.g. http://192.168.1.10)",
+				EnvVars:  []string{"AGENT_SERVER"},
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "token",
+				Usage:    "ingest token configured on the central server",
+				EnvVars:  []string{"AGENT_TOKEN"},
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:    "name",
+				Usage:   "agent name shown on the central server (defaults to hostname)",
+				EnvVars: []string{"AGENT_NAME"},
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Usage: "how often a snapshot is collected and pushed",
+				Value: 5 * time.Second,
+			},
+			&cli.IntFlag{
+				Name:  "buffer",
+				Usage: "max snapshots kept in memory while the server is unreachable (lost if the agent restarts)",
+				Value: 720,
+			},
+		}, append(logFlags(), resourceFlags()...)...),
+		Before: setupLogging,
+		Action: func(c *cli.Context) error {
+			resources, err := resourceOptions(c)
+			if err != nil {
+				return err
+			}
+			agent, err := http.NewAgent(http.AgentOptions{
+				ServerURL:   c.String("server"),
+				Token:       c.String("token"),
+				Name:        c.String("name"),
+				Interval:    c.Duration("interval"),
+				MaxBuffered: c.Int("buffer"),
+				Resources:   resources,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create agent: %w", err)
+			}
+			return agent.Run()
+		},
+	}
+}
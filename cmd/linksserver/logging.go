@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// logFlags are shared by the server and the agent subcommand.
+func logFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "log-level",
+			Usage:   "debug, info, warn or error",
+			EnvVars: []string{"LOG_LEVEL"},
+			Value:   "info",
+		},
+		&cli.StringFlag{
+			Name:    "log-format",
+			Usage:   "text or json",
+			EnvVars: []string{"LOG_FORMAT"},
+			Value:   "text",
+		},
+	}
+}
+
+// setupLogging installs the default slog logger used by every package.
+func setupLogging(c *cli.Context) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.String("log-level"))); err != nil {
+		return fmt.Errorf("invalid --log-level %q, expected debug, info, warn or error", c.String("log-level"))
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(c.String("log-format")) {
+	case "", "text":
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	default:
+		return fmt.Errorf("invalid --log-format %q, expected text or json", c.String("log-format"))
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"runtime"
 	"runtime/debug"
+	"strconv"
 
 	"github.com/tomek7667/links/internal/http"
 	"github.com/tomek7667/links/internal/json"
@@ -17,36 +19,265 @@ func main() {
 		Description: "simple http server displaying links to your services with local json database",
 		Usage:       "serve or manage the linksserver binary (use subcommands)",
 		Version:     appVersion(),
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.IntFlag{
 				Name:    "port",
 				Aliases: []string{"p"},
 				EnvVars: []string{"PORT"},
 				Value:   80,
 			},
-		},
+			&cli.StringSliceFlag{
+				Name:    "listen",
+				Usage:   "address to serve on instead of --port: host:port, unix:/path.sock, systemd or systemd:NAME (repeatable)",
+				EnvVars: []string{"LISTEN"},
+			},
+			&cli.StringFlag{
+				Name:    "unix-socket-mode",
+				Usage:   "permissions of unix: sockets, in octal",
+				EnvVars: []string{"UNIX_SOCKET_MODE"},
+				Value:   "0660",
+			},
+			&cli.StringFlag{
+				Name:    "base-path",
+				Usage:   "serve below this path prefix, e.g. /links behind a reverse proxy",
+				EnvVars: []string{"BASE_PATH"},
+			},
+			&cli.StringSliceFlag{
+				Name:    "trusted-proxy",
+				Usage:   "CIDR or address allowed to set X-Forwarded-For, or \"unix\" for unix socket peers (repeatable)",
+				EnvVars: []string{"TRUSTED_PROXIES"},
+			},
+			&cli.StringFlag{
+				Name:    "ingest-token",
+				Usage:   "accept resource snapshots pushed by agents using this bearer token",
+				EnvVars: []string{"INGEST_TOKEN"},
+			},
+			&cli.StringFlag{
+				Name:    "tls-cert",
+				Usage:   "serve HTTPS using this PEM certificate (reloaded when the file changes)",
+				EnvVars: []string{"TLS_CERT"},
+			},
+			&cli.StringFlag{
+				Name:    "tls-key",
+				Usage:   "PEM private key for --tls-cert",
+				EnvVars: []string{"TLS_KEY"},
+			},
+			&cli.BoolFlag{
+				Name:    "tls-self-signed",
+				Usage:   "generate a self-signed certificate at --tls-cert/--tls-key (default ./linksserver.crt and ./linksserver.key) if missing",
+				EnvVars: []string{"TLS_SELF_SIGNED"},
+			},
+			&cli.IntFlag{
+				Name:    "http-redirect-port",
+				Usage:   "with HTTPS enabled, also listen for plain HTTP on this port and redirect to HTTPS",
+				EnvVars: []string{"HTTP_REDIRECT_PORT"},
+			},
+			&cli.StringSliceFlag{
+				Name:    "acme-domain",
+				Usage:   "obtain and renew a certificate for this domain via ACME (repeatable)",
+				EnvVars: []string{"ACME_DOMAINS"},
+			},
+			&cli.StringFlag{
+				Name:    "acme-email",
+				Usage:   "contact email for the ACME account",
+				EnvVars: []string{"ACME_EMAIL"},
+			},
+			&cli.StringFlag{
+				Name:    "acme-directory",
+				Usage:   "ACME directory URL (default Let's Encrypt)",
+				EnvVars: []string{"ACME_DIRECTORY"},
+			},
+			&cli.StringFlag{
+				Name:    "acme-ca",
+				Usage:   "PEM file with the CA to trust when talking to the ACME server (e.g. Pebble)",
+				EnvVars: []string{"ACME_CA"},
+			},
+			&cli.StringFlag{
+				Name:    "acme-cache-dir",
+				Usage:   "directory for the ACME account key and certificates",
+				EnvVars: []string{"ACME_CACHE_DIR"},
+				Value:   "./acme",
+			},
+			&cli.StringSliceFlag{
+				Name:    "log-ignore-path",
+				Usage:   "request path glob (relative to --base-path) only logged at debug level (repeatable)",
+				EnvVars: []string{"LOG_IGNORE_PATHS"},
+				Value:   cli.NewStringSlice(http.DefaultLogIgnorePaths...),
+			},
+			&cli.StringFlag{
+				Name:    "rate-limit-read",
+				Usage:   "per-client limit for GET /api/ requests, e.g. 30/s or 30/m:10 (burst); 0 disables",
+				EnvVars: []string{"RATE_LIMIT_READ"},
+				Value:   "30/s",
+			},
+			&cli.StringFlag{
+				Name:    "rate-limit-write",
+				Usage:   "per-client limit for saving and deleting links",
+				EnvVars: []string{"RATE_LIMIT_WRITE"},
+				Value:   "60/m",
+			},
+			&cli.StringFlag{
+				Name:    "rate-limit-ingest",
+				Usage:   "per-agent limit for POST /api/ingest",
+				EnvVars: []string{"RATE_LIMIT_INGEST"},
+				Value:   "10/s",
+			},
+			&cli.IntFlag{
+				Name:    "max-body-size",
+				Usage:   "largest link request body in KB",
+				EnvVars: []string{"MAX_BODY_SIZE"},
+				Value:   http.DefaultMaxBodyBytes >> 10,
+			},
+			&cli.IntFlag{
+				Name:    "max-ingest-body-size",
+				Usage:   "largest POST /api/ingest body in MB",
+				EnvVars: []string{"MAX_INGEST_BODY_SIZE"},
+				Value:   http.DefaultMaxIngestBodyBytes >> 20,
+			},
+			&cli.IntFlag{
+				Name:    "max-links",
+				Usage:   "most links the database accepts (0 disables)",
+				EnvVars: []string{"MAX_LINKS"},
+				Value:   1000,
+			},
+			&cli.IntFlag{
+				Name:    "max-field-length",
+				Usage:   "longest title, url or unit in bytes (0 disables)",
+				EnvVars: []string{"MAX_FIELD_LENGTH"},
+				Value:   2048,
+			},
+			&cli.StringFlag{
+				Name:    "access-log",
+				Usage:   "also write every request to this file",
+				EnvVars: []string{"ACCESS_LOG"},
+			},
+			&cli.StringFlag{
+				Name:    "access-log-format",
+				Usage:   "combined, common or json",
+				EnvVars: []string{"ACCESS_LOG_FORMAT"},
+				Value:   "combined",
+			},
+			&cli.IntFlag{
+				Name:    "access-log-max-size",
+				Usage:   "rotate the access log when it reaches this many MB (0 disables)",
+				EnvVars: []string{"ACCESS_LOG_MAX_SIZE"},
+				Value:   10,
+			},
+			&cli.DurationFlag{
+				Name:    "access-log-rotate",
+				Usage:   "also rotate the access log on this interval, e.g. 24h (0 disables)",
+				EnvVars: []string{"ACCESS_LOG_ROTATE"},
+			},
+			&cli.IntFlag{
+				Name:    "access-log-max-backups",
+				Usage:   "rotated access logs to keep (0 keeps all)",
+				EnvVars: []string{"ACCESS_LOG_MAX_BACKUPS"},
+				Value:   7,
+			},
+			&cli.BoolFlag{
+				Name:    "access-log-compress",
+				Usage:   "gzip rotated access logs",
+				EnvVars: []string{"ACCESS_LOG_COMPRESS"},
+				Value:   true,
+			},
+		}, append(logFlags(), resourceFlags()...)...),
 		Commands: []*cli.Command{
 			cmdUpdate(),
 			cmdCompleteUpdate(),
+			cmdAgent(),
+			cmdAdd(),
 		},
 		CommandNotFound: func(c *cli.Context, command string) {
 			fmt.Fprintf(os.Stderr, "unknown command %q\n\n", command)
 			cli.ShowAppHelpAndExit(c, 1)
 		},
 		Action: func(c *cli.Context) error {
-			db, err := json.New()
+			resources, err := resourceOptions(c)
+			if err != nil {
+				return err
+			}
+			tls := http.TLSOptions{
+				CertFile:     c.String("tls-cert"),
+				KeyFile:      c.String("tls-key"),
+				SelfSigned:   c.Bool("tls-self-signed"),
+				RedirectPort: c.Int("http-redirect-port"),
+				ACME: http.ACMEOptions{
+					Domains:      c.StringSlice("acme-domain"),
+					Email:        c.String("acme-email"),
+					DirectoryURL: c.String("acme-directory"),
+					CAFile:       c.String("acme-ca"),
+					CacheDir:     c.String("acme-cache-dir"),
+				},
+			}
+			if tls.RedirectPort != 0 && tls.CertFile == "" && !tls.SelfSigned && len(tls.ACME.Domains) == 0 {
+				return fmt.Errorf("--http-redirect-port requires --tls-cert, --tls-self-signed or --acme-domain")
+			}
+			socketMode, err := strconv.ParseUint(c.String("unix-socket-mode"), 8, 32)
+			if err != nil || socketMode > 0o777 {
+				return fmt.Errorf("invalid --unix-socket-mode %q", c.String("unix-socket-mode"))
+			}
+			proxies, err := http.ParseTrustedProxies(c.StringSlice("trusted-proxy"))
+			if err != nil {
+				return err
+			}
+			var limits http.Limits
+			for _, l := range []struct {
+				flag  string
+				limit *http.RateLimit
+			}{
+				{"rate-limit-read", &limits.Read},
+				{"rate-limit-write", &limits.Write},
+				{"rate-limit-ingest", &limits.Ingest},
+			} {
+				rl, err := http.ParseRateLimit(c.String(l.flag))
+				if err != nil {
+					return fmt.Errorf("--%s: %w", l.flag, err)
+				}
+				*l.limit = rl
+			}
+			if c.Int("max-body-size") <= 0 || c.Int("max-ingest-body-size") <= 0 {
+				return fmt.Errorf("--max-body-size and --max-ingest-body-size must be positive")
+			}
+			limits.MaxBodyBytes = int64(c.Int("max-body-size")) << 10
+			limits.MaxIngestBodyBytes = int64(c.Int("max-ingest-body-size")) << 20
+
+			db, err := json.New(json.Limits{
+				MaxLinks:       c.Int("max-links"),
+				MaxFieldLength: c.Int("max-field-length"),
+			})
 			if err != nil {
 				return fmt.Errorf("failed to create json database: %w", err)
 			}
 			port := c.Int("port")
-			server := http.New(port, db)
+			server := http.New(port, db, http.Options{
+				Build:          appBuildInfo(),
+				IngestToken:    c.String("ingest-token"),
+				Listen:         c.StringSlice("listen"),
+				UnixSocketMode: os.FileMode(socketMode),
+				BasePath:       c.String("base-path"),
+				TrustedProxies: proxies,
+				LogIgnorePaths: c.StringSlice("log-ignore-path"),
+				Limits:         limits,
+				AccessLog: http.AccessLogOptions{
+					Path:         c.String("access-log"),
+					Format:       c.String("access-log-format"),
+					MaxSizeBytes: int64(c.Int("access-log-max-size")) << 20,
+					RotateEvery:  c.Duration("access-log-rotate"),
+					MaxBackups:   c.Int("access-log-max-backups"),
+					Compress:     c.Bool("access-log-compress"),
+				},
+				TLS:       tls,
+				Resources: resources,
+			})
 			return server.Serve()
 		},
+		Before:       setupLogging,
 		BashComplete: cli.ShowCompletions,
 	}
 
 	if err := app.Run(os.Args); err != nil {
-		log.Fatal(err)
+		slog.Error(err.Error())
+		os.Exit(1)
 	}
 }
 
@@ -55,30 +286,17 @@ func appVersion() string {
 	if !ok || bi == nil {
 		return "unknown"
 	}
+	return printableVersion(metaFromBuildInfo(bi))
+}
 
-	version := bi.Main.Version
-	var rev string
-	var modified bool
-	for _, s := range bi.Settings {
-		switch s.Key {
-		case "vcs.revision":
-			rev = s.Value
-		case "vcs.modified":
-			modified = s.Value == "true"
-		}
-	}
-
-	if version != "" && version != "(devel)" {
-		return version
-	}
-	if rev != "" {
-		if modified {
-			return rev + " (modified)"
-		}
-		return rev
-	}
-	if version != "" {
-		return version
+// appBuildInfo is served by /api/version.
+func appBuildInfo() http.BuildInfo {
+	info := http.BuildInfo{Version: appVersion(), GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok && bi != nil {
+		m := metaFromBuildInfo(bi)
+		info.Revision = m.revision
+		info.Modified = m.modified
+		info.GoVersion = bi.GoVersion
 	}
-	return "unknown"
+	return info
 }
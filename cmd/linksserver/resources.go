@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomek7667/links/internal/http"
+	"github.com/urfave/cli/v2"
+)
+
+// resourceFlags configure the ResourceMonitor and are shared by the server
+// and the agent subcommand.
+func resourceFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "smart",
+			Usage:   "report drive health via smartctl (needs smartmontools and root or disk group access)",
+			EnvVars: []string{"SMART"},
+		},
+		&cli.StringFlag{
+			Name:    "container-socket",
+			Usage:   "Docker/Podman API unix socket (default: auto-detect, \"none\" to disable)",
+			EnvVars: []string{"CONTAINER_SOCKET"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "systemd-unit",
+			Usage:   "systemd unit to report the status of (repeatable; units set on links are always included)",
+			EnvVars: []string{"SYSTEMD_UNITS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "nut-ups",
+			Usage:   "UPS to query from a NUT upsd as ups@host[:port] (repeatable)",
+			EnvVars: []string{"NUT_UPS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "disk-include",
+			Usage:   "mountpoint glob to always show in the disks table (\"/srv/**\" matches everything below /srv)",
+			EnvVars: []string{"DISK_INCLUDE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "disk-exclude",
+			Usage:   "mountpoint glob to hide from the disks table",
+			EnvVars: []string{"DISK_EXCLUDE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "disk-include-device",
+			Usage:   "device glob to always show, e.g. \"nas:/export/*\" or \"tank/*\"",
+			EnvVars: []string{"DISK_INCLUDE_DEVICE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "disk-exclude-device",
+			Usage:   "device glob to hide",
+			EnvVars: []string{"DISK_EXCLUDE_DEVICE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "disk-include-fstype",
+			Usage:   "filesystem type to always show, e.g. nfs4",
+			EnvVars: []string{"DISK_INCLUDE_FSTYPE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "disk-exclude-fstype",
+			Usage:   "filesystem type to hide",
+			EnvVars: []string{"DISK_EXCLUDE_FSTYPE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "disk-name",
+			Usage:   "display name for a mount as MOUNTPOINT=NAME",
+			EnvVars: []string{"DISK_NAMES"},
+		},
+	}
+}
+
+func resourceOptions(c *cli.Context) (http.ResourceMonitorOptions, error) {
+	names := make(map[string]string)
+	for _, v := range c.StringSlice("disk-name") {
+		mp, name, ok := strings.Cut(v, "=")
+		if !ok || strings.TrimSpace(mp) == "" || strings.TrimSpace(name) == "" {
+			return http.ResourceMonitorOptions{}, fmt.Errorf("invalid --disk-name %q, expected MOUNTPOINT=NAME", v)
+		}
+		names[strings.TrimSpace(mp)] = strings.TrimSpace(name)
+	}
+
+	return http.ResourceMonitorOptions{
+		SMART:           c.Bool("smart"),
+		ContainerSocket: c.String("container-socket"),
+		SystemdUnits:    c.StringSlice("systemd-unit"),
+		NUTUPS:          c.StringSlice("nut-ups"),
+		Disks: http.DiskRules{
+			IncludeMounts:  c.StringSlice("disk-include"),
+			ExcludeMounts:  c.StringSlice("disk-exclude"),
+			IncludeDevices: c.StringSlice("disk-include-device"),
+			ExcludeDevices: c.StringSlice("disk-exclude-device"),
+			IncludeFSTypes: c.StringSlice("disk-include-fstype"),
+			ExcludeFSTypes: c.StringSlice("disk-exclude-fstype"),
+			Names:          names,
+		},
+	}, nil
+}
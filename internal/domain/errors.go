@@ -0,0 +1,7 @@
+package domain
+
+import "errors"
+
+// ErrLimitExceeded is returned by stores when saving a link would go past a
+// configured limit (number of links, field length).
+var ErrLimitExceeded = errors.New("limit exceeded")
@@ -1,6 +1,11 @@
 package domain
 
-type Link struct {
-	Title string `json:"title"`
-	Url   string `json:"url"`
-}
+import "github.com/tomek7667/links/pkg/api"
+
+// Link and the validation errors are part of the API; see pkg/api for them
+// and for the rules links are normalised by.
+type (
+	Link            = api.Link
+	FieldError      = api.FieldError
+	ValidationError = api.ValidationError
+)
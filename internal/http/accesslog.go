@@ -0,0 +1,372 @@
+package http
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	accessLogTimeLayout   = "02/Jan/2006:15:04:05 -0700"
+	accessLogBackupLayout = "20060102-150405"
+)
+
+type AccessLogOptions struct {
+	// Path of the log file; the access log is off when empty.
+	Path string
+	// Format is "combined" (default), "common" or "json".
+	Format string
+	// MaxSizeBytes rotates the file once it would grow past this size.
+	MaxSizeBytes int64
+	// RotateEvery also rotates on this interval (e.g. 24h rotates at
+	// midnight UTC). Zero rotates on size only.
+	RotateEvery time.Duration
+	// MaxBackups is how many rotated files are kept; 0 keeps all.
+	MaxBackups int
+	// Compress gzips rotated files.
+	Compress bool
+}
+
+// accessLogger writes one line per request to a file it rotates itself, so
+// request history survives reboots without logrotate.
+type accessLogger struct {
+	opts AccessLogOptions
+
+	mu         sync.Mutex
+	file       *os.File
+	size       int64
+	nextRotate time.Time
+	// compressing tracks background gzip jobs so Close can wait for them.
+	compressing sync.WaitGroup
+	// cleanupMu runs one gzip-and-prune job at a time, so pruning never
+	// sees a backup another job is still compressing.
+	cleanupMu sync.Mutex
+}
+
+// newAccessLogger returns nil when no path is configured. The file is
+// opened by open once the server starts.
+func newAccessLogger(opts AccessLogOptions) *accessLogger {
+	if opts.Path == "" {
+		return nil
+	}
+	if opts.Format == "" {
+		opts.Format = "combined"
+	}
+	return &accessLogger{opts: opts}
+}
+
+func (l *accessLogger) open() error {
+	switch l.opts.Format {
+	case "combined", "common", "json":
+	default:
+		return fmt.Errorf("invalid access log format %q, expected combined, common or json", l.opts.Format)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.openLocked(time.Now())
+}
+
+func (l *accessLogger) openLocked(now time.Time) error {
+	if dir := filepath.Dir(l.opts.Path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(l.opts.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open access log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	l.file = f
+	l.size = info.Size()
+	l.nextRotate = l.nextRotation(now)
+	return nil
+}
+
+// nextRotation is the end of the RotateEvery interval now falls in, or zero
+// without time-based rotation.
+func (l *accessLogger) nextRotation(now time.Time) time.Time {
+	if l.opts.RotateEvery <= 0 {
+		return time.Time{}
+	}
+	return now.Truncate(l.opts.RotateEvery).Add(l.opts.RotateEvery)
+}
+
+func (l *accessLogger) Close() error {
+	l.mu.Lock()
+	var err error
+	if l.file != nil {
+		err = l.file.Close()
+		l.file = nil
+	}
+	l.mu.Unlock()
+	l.compressing.Wait()
+	return err
+}
+
+func (l *accessLogger) log(r *http.Request, status, bytes int, elapsed time.Duration, now time.Time) {
+	line := formatAccessLine(l.opts.Format, r, status, bytes, elapsed, now)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return
+	}
+	if l.needsRotation(int64(len(line)), now) {
+		if err := l.rotateLocked(now); err != nil {
+			slog.Error("access log rotation failed", "path", l.opts.Path, "err", err)
+			if l.file == nil {
+				return
+			}
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		slog.Error("access log write failed", "path", l.opts.Path, "err", err)
+	}
+}
+
+// needsRotation reports whether the file should be rotated before writing
+// next more bytes. An empty file is never rotated; when its interval is up
+// the next one starts instead.
+func (l *accessLogger) needsRotation(next int64, now time.Time) bool {
+	due := !l.nextRotate.IsZero() && !now.Before(l.nextRotate)
+	if l.size == 0 {
+		if due {
+			l.nextRotate = l.nextRotation(now)
+		}
+		return false
+	}
+	if l.opts.MaxSizeBytes > 0 && l.size+next > l.opts.MaxSizeBytes {
+		return true
+	}
+	return due
+}
+
+func (l *accessLogger) rotateLocked(now time.Time) error {
+	if err := l.file.Close(); err != nil {
+		slog.Warn("closing access log before rotation", "err", err)
+	}
+	l.file = nil
+
+	backup := l.opts.Path + "." + now.UTC().Format(accessLogBackupLayout)
+	for i := 1; fileExists(backup) || fileExists(backup+".gz"); i++ {
+		backup = l.opts.Path + "." + now.UTC().Format(accessLogBackupLayout) + "-" + strconv.Itoa(i)
+	}
+	if err := os.Rename(l.opts.Path, backup); err != nil {
+		// Keep appending to the current file rather than losing lines.
+		_ = l.openLocked(now)
+		return err
+	}
+	if err := l.openLocked(now); err != nil {
+		return err
+	}
+
+	l.compressing.Add(1)
+	go func() {
+		defer l.compressing.Done()
+		l.cleanupMu.Lock()
+		defer l.cleanupMu.Unlock()
+		if l.opts.Compress {
+			if err := gzipFile(backup); err != nil {
+				slog.Error("access log compression failed", "path", backup, "err", err)
+			}
+		}
+		if err := pruneAccessLogs(l.opts.Path, l.opts.MaxBackups); err != nil {
+			slog.Error("access log cleanup failed", "path", l.opts.Path, "err", err)
+		}
+	}()
+	return nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// gzipFile replaces path with path.gz.
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(path+".gz.tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	zw.Name = filepath.Base(path)
+	if _, err := io.Copy(zw, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(out.Name())
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		_ = out.Close()
+		_ = os.Remove(out.Name())
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(out.Name())
+		return err
+	}
+	if err := os.Rename(out.Name(), path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// accessLogBackup is a rotated file, ordered by the time and counter in its
+// name: path.20060102-150405[-N][.gz].
+type accessLogBackup struct {
+	name  string
+	at    time.Time
+	count int
+}
+
+func parseAccessLogBackup(path, name string) (accessLogBackup, bool) {
+	suffix, ok := strings.CutPrefix(name, path+".")
+	if !ok || strings.HasSuffix(suffix, ".tmp") {
+		return accessLogBackup{}, false
+	}
+	suffix = strings.TrimSuffix(suffix, ".gz")
+	if len(suffix) < len(accessLogBackupLayout) {
+		return accessLogBackup{}, false
+	}
+	at, err := time.Parse(accessLogBackupLayout, suffix[:len(accessLogBackupLayout)])
+	if err != nil {
+		return accessLogBackup{}, false
+	}
+	b := accessLogBackup{name: name, at: at}
+	if rest := suffix[len(accessLogBackupLayout):]; rest != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(rest, "-"))
+		if err != nil || !strings.HasPrefix(rest, "-") {
+			return accessLogBackup{}, false
+		}
+		b.count = n
+	}
+	return b, true
+}
+
+// pruneAccessLogs deletes the oldest rotated files beyond keep. Files that
+// don't look like backups are left alone.
+func pruneAccessLogs(path string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	matches, err := filepath.Glob(path + ".[0-9]*")
+	if err != nil {
+		return err
+	}
+	var backups []accessLogBackup
+	for _, m := range matches {
+		if b, ok := parseAccessLogBackup(path, m); ok {
+			backups = append(backups, b)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].at.Equal(backups[j].at) {
+			return backups[i].at.Before(backups[j].at)
+		}
+		return backups[i].count < backups[j].count
+	})
+	var errs []string
+	for len(backups) > keep {
+		if err := os.Remove(backups[0].name); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err.Error())
+		}
+		backups = backups[1:]
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// formatAccessLine renders Apache common/combined log format or a JSON
+// object, newline terminated.
+func formatAccessLine(format string, r *http.Request, status, bytes int, elapsed time.Duration, now time.Time) []byte {
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "" || host == "@" {
+		host = "-"
+	}
+
+	if format == "json" {
+		b, _ := json.Marshal(struct {
+			Time       string  `json:"time"`
+			Remote     string  `json:"remote"`
+			Method     string  `json:"method"`
+			URI        string  `json:"uri"`
+			Proto      string  `json:"proto"`
+			Status     int     `json:"status"`
+			Bytes      int     `json:"bytes"`
+			DurationMs float64 `json:"durationMs"`
+			Referer    string  `json:"referer,omitempty"`
+			UserAgent  string  `json:"userAgent,omitempty"`
+			RequestID  string  `json:"requestId,omitempty"`
+		}{
+			Time:       now.Format(time.RFC3339Nano),
+			Remote:     host,
+			Method:     r.Method,
+			URI:        r.RequestURI,
+			Proto:      r.Proto,
+			Status:     status,
+			Bytes:      bytes,
+			DurationMs: float64(elapsed.Microseconds()) / 1000,
+			Referer:    r.Referer(),
+			UserAgent:  r.UserAgent(),
+			RequestID:  middleware.GetReqID(r.Context()),
+		})
+		return append(b, '\n')
+	}
+
+	size := "-"
+	if bytes > 0 {
+		size = strconv.Itoa(bytes)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s - - [%s] \"%s %s %s\" %d %s",
+		host, now.Format(accessLogTimeLayout), r.Method, escapeAccessField(r.RequestURI), r.Proto, status, size)
+	if format == "combined" {
+		fmt.Fprintf(&sb, " \"%s\" \"%s\"", accessFieldOrDash(r.Referer()), accessFieldOrDash(r.UserAgent()))
+	}
+	sb.WriteByte('\n')
+	return []byte(sb.String())
+}
+
+func accessFieldOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return escapeAccessField(s)
+}
+
+// escapeAccessField keeps client-controlled values from breaking the line
+// format (quotes, newlines).
+func escapeAccessField(s string) string {
+	q := strconv.Quote(s)
+	return q[1 : len(q)-1]
+}
@@ -0,0 +1,194 @@
+package http
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readAccessLogs returns the files next to path, sorted, and the lines in
+// each, gunzipping compressed ones.
+func readAccessLogs(t *testing.T, path string) (names []string, lines map[string]int) {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines = make(map[string]int)
+	for _, e := range entries {
+		name := e.Name()
+		f, err := os.Open(filepath.Join(filepath.Dir(path), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r io.Reader = f
+		if strings.HasSuffix(name, ".gz") {
+			zr, err := gzip.NewReader(f)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			r = zr
+		}
+		b, err := io.ReadAll(r)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		names = append(names, name)
+		lines[name] = strings.Count(string(b), "\n")
+	}
+	slices.Sort(names)
+	return names, lines
+}
+
+func TestAccessLogRotation(t *testing.T) {
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	tests := []struct {
+		name   string
+		opts   AccessLogOptions
+		writes []time.Time
+		// want maps each file to its number of lines.
+		want map[string]int
+	}{
+		{
+			name:   "size",
+			opts:   AccessLogOptions{MaxSizeBytes: 1},
+			writes: []time.Time{at(0), at(time.Second), at(2 * time.Second)},
+			want: map[string]int{
+				"access.log":                 1,
+				"access.log.20260301-100001": 1,
+				"access.log.20260301-100002": 1,
+			},
+		},
+		{
+			name:   "size within one second",
+			opts:   AccessLogOptions{MaxSizeBytes: 1},
+			writes: []time.Time{at(0), at(0), at(0)},
+			want: map[string]int{
+				"access.log":                   1,
+				"access.log.20260301-100000":   1,
+				"access.log.20260301-100000-1": 1,
+			},
+		},
+		{
+			name:   "interval",
+			opts:   AccessLogOptions{RotateEvery: time.Hour},
+			writes: []time.Time{at(0), at(30 * time.Minute), at(65 * time.Minute), at(70 * time.Minute)},
+			want: map[string]int{
+				"access.log":                 2,
+				"access.log.20260301-110500": 2,
+			},
+		},
+		{
+			// The first interval passes with nothing written; the file
+			// isn't rotated on the next write, nor on the one after.
+			name:   "interval with an empty file",
+			opts:   AccessLogOptions{RotateEvery: time.Hour},
+			writes: []time.Time{at(150 * time.Minute), at(160 * time.Minute)},
+			want: map[string]int{
+				"access.log": 2,
+			},
+		},
+		{
+			name:   "retention",
+			opts:   AccessLogOptions{MaxSizeBytes: 1, MaxBackups: 2},
+			writes: []time.Time{at(0), at(time.Second), at(2 * time.Second), at(3 * time.Second), at(4 * time.Second)},
+			want: map[string]int{
+				"access.log":                 1,
+				"access.log.20260301-100003": 1,
+				"access.log.20260301-100004": 1,
+			},
+		},
+		{
+			name:   "compression",
+			opts:   AccessLogOptions{MaxSizeBytes: 1, MaxBackups: 1, Compress: true},
+			writes: []time.Time{at(0), at(time.Second), at(2 * time.Second)},
+			want: map[string]int{
+				"access.log":                    1,
+				"access.log.20260301-100002.gz": 1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Path = filepath.Join(t.TempDir(), "access.log")
+			l := newAccessLogger(tt.opts)
+			l.mu.Lock()
+			err := l.openLocked(start)
+			l.mu.Unlock()
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := httptest.NewRequest("GET", "/api/links", nil)
+			for _, now := range tt.writes {
+				l.log(r, 200, 2, time.Millisecond, now)
+			}
+			if err := l.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			names, lines := readAccessLogs(t, tt.opts.Path)
+			var want []string
+			for name := range tt.want {
+				want = append(want, name)
+			}
+			slices.Sort(want)
+			if !slices.Equal(names, want) {
+				t.Fatalf("files = %q, want %q", names, want)
+			}
+			for name, n := range tt.want {
+				if lines[name] != n {
+					t.Errorf("%s has %d lines, want %d", name, lines[name], n)
+				}
+			}
+		})
+	}
+}
+
+func TestPruneAccessLogs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	// Oldest first. By name, "-10" and "-2" sort before ".gz" and "-1".
+	backups := []string{
+		"access.log.20260301-100000.gz",
+		"access.log.20260301-100000-1.gz",
+		"access.log.20260301-100000-2",
+		"access.log.20260301-100000-10",
+		"access.log.20260302-000000",
+	}
+	for _, name := range append(slices.Clone(backups), "access.log", "access.log.2-notes", "access.log.20260303-000000.gz.tmp") {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := pruneAccessLogs(path, 2); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{
+		"access.log",
+		"access.log.2-notes",
+		"access.log.20260301-100000-10",
+		"access.log.20260302-000000",
+		"access.log.20260303-000000.gz.tmp",
+	}
+	if !slices.Equal(names, want) {
+		t.Errorf("files = %q\nwant %q", names, want)
+	}
+}
@@ -0,0 +1,185 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const defaultACMECacheDir = "./acme"
+
+type ACMEOptions struct {
+	// Domains the certificate is requested for; ACME is off when empty.
+	Domains []string
+	Email   string
+	// DirectoryURL defaults to Let's Encrypt production.
+	DirectoryURL string
+	// CAFile is a PEM bundle trusted for talking to the ACME server, for
+	// private CAs and test servers such as Pebble.
+	CAFile string
+	// CacheDir holds the account key and issued certificates.
+	CacheDir string
+}
+
+func (o ACMEOptions) enabled() bool {
+	return len(o.Domains) > 0
+}
+
+// newACMEManager returns a manager that answers TLS-ALPN-01 challenges on the
+// HTTPS listener and HTTP-01 challenges through its HTTPHandler. Certificates
+// are renewed in the background and served without a restart.
+func newACMEManager(opts ACMEOptions) (*autocert.Manager, error) {
+	domains := make([]string, 0, len(opts.Domains))
+	for _, d := range opts.Domains {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no ACME domains given")
+	}
+
+	cacheDir := opts.CacheDir
+	if cacheDir == "" {
+		cacheDir = defaultACMECacheDir
+	}
+	client := &acme.Client{
+		DirectoryURL: opts.DirectoryURL,
+		UserAgent:    "linksserver",
+	}
+	if client.DirectoryURL == "" {
+		client.DirectoryURL = acme.LetsEncryptURL
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ACME CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in '%s'", opts.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	client.HTTPClient = &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: newACMEOrderTransport(transport),
+	}
+
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cacheDir),
+		HostPolicy: acmeHostPolicy(domains),
+		Email:      opts.Email,
+		Client:     client,
+	}, nil
+}
+
+// acmeHostPolicy is autocert.HostWhitelist that also accepts "host:port",
+// which the HTTP-01 handler passes through when the redirect listener isn't
+// on port 80 (e.g. behind a port forward, or testing against Pebble).
+func acmeHostPolicy(domains []string) autocert.HostPolicy {
+	allowed := autocert.HostWhitelist(domains...)
+	return func(ctx context.Context, host string) error {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		return allowed(ctx, host)
+	}
+}
+
+// acmeOrderTransport works around x/crypto/acme's CreateOrderCert, which
+// polls the order at the Location header of the finalize response when the
+// order isn't valid yet. RFC 8555 only requires Location on newOrder, and
+// servers that finalize asynchronously (Pebble, some private CAs) leave it
+// out, so the poll fails with `Post "": unsupported protocol scheme ""` and
+// no certificate is ever issued.
+//
+// newOrder responses carry both the order URL (Location) and its finalize
+// URL (in the body); the transport remembers that pair and sets Location on
+// finalize responses that lack it. It can go once x/crypto polls the order
+// URL it already knows.
+type acmeOrderTransport struct {
+	base http.RoundTripper
+	// now is time.Now outside tests.
+	now func() time.Time
+
+	mu sync.Mutex
+	// orders maps finalize URL -> order. An order is forgotten once it is
+	// finalized, whether that worked or not, or after acmeOrderTTL for
+	// orders whose authorizations failed and that are never finalized.
+	orders map[string]acmeOrder
+}
+
+type acmeOrder struct {
+	url     string
+	created time.Time
+}
+
+// acmeOrderTTL is far longer than autocert takes from newOrder to finalize.
+const acmeOrderTTL = time.Hour
+
+func newACMEOrderTransport(base http.RoundTripper) *acmeOrderTransport {
+	return &acmeOrderTransport{base: base, now: time.Now, orders: make(map[string]acmeOrder)}
+}
+
+func (t *acmeOrderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.base.RoundTrip(req)
+	if err != nil || req.Method != http.MethodPost {
+		return res, err
+	}
+
+	// Finalize is posted once per order.
+	reqURL := req.URL.String()
+	t.mu.Lock()
+	order, ok := t.orders[reqURL]
+	delete(t.orders, reqURL)
+	t.mu.Unlock()
+	if ok {
+		if res.StatusCode < 300 && res.Header.Get("Location") == "" {
+			res.Header.Set("Location", order.url)
+		}
+		return res, nil
+	}
+
+	// Remember order URLs from newOrder responses.
+	location := res.Header.Get("Location")
+	if res.StatusCode >= 300 || location == "" || !strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") {
+		return res, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	var newOrder struct {
+		Finalize string `json:"finalize"`
+	}
+	if json.Unmarshal(body, &newOrder) == nil && newOrder.Finalize != "" {
+		now := t.now()
+		t.mu.Lock()
+		for finalize, o := range t.orders {
+			if now.Sub(o.created) >= acmeOrderTTL {
+				delete(t.orders, finalize)
+			}
+		}
+		t.orders[newOrder.Finalize] = acmeOrder{url: location, created: now}
+		t.mu.Unlock()
+	}
+	return res, nil
+}
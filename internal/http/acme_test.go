@@ -0,0 +1,173 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestACMEOrderTransport(t *testing.T) {
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch id := path.Base(r.URL.Path); path.Dir(r.URL.Path) {
+		case "/new-order":
+			w.Header().Set("Location", srvURL+"/order/"+id)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"status": "pending", "finalize": "` + srvURL + `/finalize/` + id + `"}`))
+		case "/finalize":
+			if id == "bad" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"type": "urn:ietf:params:acme:error:orderNotReady"}`))
+				return
+			}
+			// Processing, and no Location, like Pebble.
+			_, _ = w.Write([]byte(`{"status": "processing"}`))
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	transport := newACMEOrderTransport(http.DefaultTransport)
+	transport.now = func() time.Time { return now }
+	c := &http.Client{Transport: transport}
+	post := func(path string) *http.Response {
+		t.Helper()
+		res, err := c.Post(srv.URL+path, "application/jose+json", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		return res
+	}
+	pending := func() []string {
+		transport.mu.Lock()
+		defer transport.mu.Unlock()
+		var ids []string
+		for finalize := range transport.orders {
+			ids = append(ids, path.Base(finalize))
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	if res := post("/new-order/1"); res.Header.Get("Location") != srv.URL+"/order/1" {
+		t.Fatalf("newOrder Location = %q", res.Header.Get("Location"))
+	}
+	if loc := post("/finalize/1").Header.Get("Location"); loc != srv.URL+"/order/1" {
+		t.Errorf("finalize Location = %q, want the order URL", loc)
+	}
+	if loc := post("/finalize/2").Header.Get("Location"); loc != "" {
+		t.Errorf("finalize of an unknown order got Location %q", loc)
+	}
+	if ids := pending(); len(ids) != 0 {
+		t.Errorf("orders after finalize = %v, want none", ids)
+	}
+
+	// A failed finalize forgets the order too.
+	post("/new-order/bad")
+	if res := post("/finalize/bad"); res.StatusCode != http.StatusForbidden || res.Header.Get("Location") != "" {
+		t.Errorf("failed finalize = %d with Location %q", res.StatusCode, res.Header.Get("Location"))
+	}
+
+	// Orders that are never finalized expire.
+	post("/new-order/3")
+	now = now.Add(acmeOrderTTL / 2)
+	post("/new-order/4")
+	now = now.Add(acmeOrderTTL / 2)
+	post("/new-order/5")
+	if ids := pending(); !slices.Equal(ids, []string{"4", "5"}) {
+		t.Errorf("orders = %v, want 4 and 5", ids)
+	}
+}
+
+// TestACMEPebble gets a certificate from a running Pebble
+// (https://github.com/letsencrypt/pebble). It is skipped unless
+// PEBBLE_DIRECTORY_URL is set, e.g.:
+//
+//	PEBBLE_VA_NOSLEEP=1 pebble -config test/config/pebble-config.json &
+//	PEBBLE_DIRECTORY_URL=https://localhost:14000/dir \
+//	PEBBLE_CA_FILE=test/certs/pebble.minica.pem \
+//	PEBBLE_DOMAIN=links.test \
+//	go test ./internal/http -run TestACMEPebble
+//
+// PEBBLE_DOMAIN must resolve to this machine for Pebble, and
+// PEBBLE_TLS_PORT / PEBBLE_HTTP_PORT must match the tlsPort and httpPort in
+// Pebble's config (5001 and 5002 by default).
+func TestACMEPebble(t *testing.T) {
+	dirURL := os.Getenv("PEBBLE_DIRECTORY_URL")
+	if dirURL == "" {
+		t.Skip("PEBBLE_DIRECTORY_URL not set")
+	}
+	domain := envOr("PEBBLE_DOMAIN", "links.test")
+	tlsAddr := net.JoinHostPort("0.0.0.0", envOr("PEBBLE_TLS_PORT", "5001"))
+	httpAddr := net.JoinHostPort("0.0.0.0", envOr("PEBBLE_HTTP_PORT", "5002"))
+
+	m, err := newACMEManager(ACMEOptions{
+		Domains:      []string{domain},
+		DirectoryURL: dirURL,
+		CAFile:       os.Getenv("PEBBLE_CA_FILE"),
+		CacheDir:     t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// TLS-ALPN-01 on the HTTPS listener, HTTP-01 on the redirect listener,
+	// as Serve sets them up.
+	tlsListener, err := tls.Listen("tcp", tlsAddr, m.TLSConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpListener, err := net.Listen("tcp", httpAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tlsSrv := &http.Server{Handler: ok}
+	httpSrv := &http.Server{Handler: m.HTTPHandler(nil)}
+	go func() { _ = tlsSrv.Serve(tlsListener) }()
+	go func() { _ = httpSrv.Serve(httpListener) }()
+	t.Cleanup(func() {
+		_ = tlsSrv.Close()
+		_ = httpSrv.Close()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	dialer := &tls.Dialer{Config: &tls.Config{
+		ServerName: domain,
+		// Pebble's issuing root is generated at startup; the chain is
+		// checked below by issuer name only.
+		InsecureSkipVerify: true,
+	}}
+	conn, err := dialer.DialContext(ctx, "tcp", tlsListener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	leaf := conn.(*tls.Conn).ConnectionState().PeerCertificates[0]
+	if err := leaf.VerifyHostname(domain); err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(leaf.Issuer.CommonName, "Pebble") {
+		t.Errorf("issuer = %q, want a Pebble intermediate", leaf.Issuer.CommonName)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
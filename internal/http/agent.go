@@ -0,0 +1,224 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	agentPushTimeout = 10 * time.Second
+	agentMaxBackoff  = 1 * time.Minute
+)
+
+type AgentOptions struct {
+	ServerURL string
+	Token     string
+	Name      string
+	// Interval between collected snapshots.
+	Interval time.Duration
+	// MaxBuffered caps how many snapshots are kept in memory while the
+	// server is unreachable; the oldest ones are dropped first. The buffer
+	// doesn't survive a restart of the agent.
+	MaxBuffered int
+
+	Resources ResourceMonitorOptions
+}
+
+// Agent runs a ResourceMonitor without the links UI and pushes snapshots to
+// a central linksserver.
+type Agent struct {
+	opts      AgentOptions
+	ingestURL string
+	client    *http.Client
+	resources *ResourceMonitor
+
+	buffer  []ResourcesSnapshot
+	dropped int
+}
+
+func NewAgent(opts AgentOptions) (*Agent, error) {
+	base := strings.TrimRight(strings.TrimSpace(opts.ServerURL), "/")
+	if base == "" {
+		return nil, fmt.Errorf("server url is required")
+	}
+	if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		return nil, fmt.Errorf("server url %q must start with http:// or https://", opts.ServerURL)
+	}
+	if opts.Token == "" {
+		return nil, fmt.Errorf("token is required")
+	}
+	if opts.Name == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve hostname (use --name): %w", err)
+		}
+		opts.Name = host
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+	if opts.MaxBuffered <= 0 {
+		opts.MaxBuffered = 720
+	}
+	return &Agent{
+		opts:      opts,
+		ingestURL: base + "/api/ingest",
+		client:    &http.Client{Timeout: agentPushTimeout},
+		resources: NewResourceMonitor(opts.Resources),
+	}, nil
+}
+
+func (a *Agent) Run() error {
+	stopResources := make(chan struct{})
+	a.resources.Start(stopResources)
+	defer close(stopResources)
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(c)
+
+	slog.Info("agent started", "agent", a.opts.Name, "server", a.ingestURL, "interval", a.opts.Interval)
+
+	ticker := time.NewTicker(a.opts.Interval)
+	defer ticker.Stop()
+
+	backoff := time.Duration(0)
+	var nextPush time.Time
+	for {
+		select {
+		case sig := <-c:
+			slog.Info("flushing and shutting down", "signal", sig.String())
+			if err := a.flush(); err != nil {
+				slog.Error("final flush failed", "lost", len(a.buffer), "err", err)
+			}
+			return nil
+		case now := <-ticker.C:
+			a.enqueue(a.resources.Snapshot(false))
+			if now.Before(nextPush) {
+				continue
+			}
+			if err := a.flush(); err != nil {
+				backoff = nextBackoff(backoff, a.opts.Interval)
+				nextPush = now.Add(backoff)
+				slog.Warn("push failed", "buffered", len(a.buffer), "retry_in", backoff, "err", err)
+				continue
+			}
+			if backoff > 0 {
+				slog.Info("reconnected", "server", a.ingestURL)
+			}
+			backoff = 0
+			nextPush = time.Time{}
+		}
+	}
+}
+
+func (a *Agent) enqueue(snap ResourcesSnapshot) {
+	a.buffer = append(a.buffer, snap)
+	if over := len(a.buffer) - a.opts.MaxBuffered; over > 0 {
+		a.buffer = append([]ResourcesSnapshot(nil), a.buffer[over:]...)
+		a.dropped += over
+	}
+}
+
+// flush replays buffered snapshots oldest first, removing each batch from
+// the buffer once the server accepted or permanently rejected it. Only
+// errors worth retrying later (network, 5xx, 408, 429) are returned.
+func (a *Agent) flush() error {
+	if a.dropped > 0 {
+		slog.Warn("buffer full, dropped oldest snapshots", "dropped", a.dropped)
+		a.dropped = 0
+	}
+	batch := ingestMaxBatch
+	for len(a.buffer) > 0 {
+		n := min(len(a.buffer), batch)
+		err := a.push(a.buffer[:n])
+		var rejected *pushRejectedError
+		switch {
+		case err == nil:
+		case !errors.As(err, &rejected):
+			return err
+		case rejected.status == http.StatusRequestEntityTooLarge && n > 1:
+			// Over the server's body size cap; retry in smaller batches.
+			batch = n / 2
+			slog.Debug("batch too large, splitting", "snapshots", n, "next", batch)
+			continue
+		default:
+			// Resending the same batch would fail the same way and block
+			// everything buffered behind it.
+			slog.Error("server rejected batch, dropping it", "snapshots", n, "err", err)
+		}
+		a.buffer = a.buffer[n:]
+	}
+	a.buffer = nil
+	return nil
+}
+
+// pushRejectedError is a 4xx answer that retrying the same batch won't fix.
+type pushRejectedError struct {
+	status int
+	err    error
+}
+
+func (e *pushRejectedError) Error() string { return e.err.Error() }
+func (e *pushRejectedError) Unwrap() error { return e.err }
+
+func (a *Agent) push(snaps []ResourcesSnapshot) error {
+	body, err := json.Marshal(IngestBatch{Agent: a.opts.Name, Snapshots: snaps})
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), agentPushTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.ingestURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.opts.Token)
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	err = fmt.Errorf("server responded %s: %s", res.Status, strings.TrimSpace(string(msg)))
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusNotFound {
+		err = errors.Join(err, errors.New("check --token and that the server runs with --ingest-token"))
+	}
+	switch {
+	case res.StatusCode == http.StatusRequestTimeout, res.StatusCode == http.StatusTooManyRequests:
+		return err
+	case res.StatusCode >= 400 && res.StatusCode < 500:
+		return &pushRejectedError{status: res.StatusCode, err: err}
+	}
+	return err
+}
+
+func nextBackoff(cur, base time.Duration) time.Duration {
+	if cur <= 0 {
+		return base
+	}
+	cur *= 2
+	if cur > agentMaxBackoff {
+		return agentMaxBackoff
+	}
+	return cur
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestAgent(t *testing.T, handler http.HandlerFunc) *Agent {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	a, err := NewAgent(AgentOptions{ServerURL: srv.URL, Token: "t", Name: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestAgentFlushSplitsOn413(t *testing.T) {
+	var received, sizes []int
+	a := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		var batch IngestBatch
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		sizes = append(sizes, len(batch.Snapshots))
+		if len(batch.Snapshots) > 10 {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		for _, s := range batch.Snapshots {
+			received = append(received, int(s.UpdatedAt))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	for i := range 25 {
+		a.enqueue(ResourcesSnapshot{UpdatedAt: int64(i)})
+	}
+
+	if err := a.flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if len(a.buffer) != 0 {
+		t.Errorf("buffer has %d snapshots left", len(a.buffer))
+	}
+	if len(received) != 25 {
+		t.Fatalf("server received %d snapshots (batches %v), want 25", len(received), sizes)
+	}
+	for i, v := range received {
+		if v != i {
+			t.Fatalf("snapshots out of order: %v", received)
+		}
+	}
+}
+
+func TestAgentFlushStatus(t *testing.T) {
+	tests := []struct {
+		status   int
+		wantErr  bool
+		buffered int
+	}{
+		{http.StatusBadRequest, false, 0},
+		{http.StatusUnauthorized, false, 0},
+		{http.StatusRequestEntityTooLarge, false, 0},
+		{http.StatusTooManyRequests, true, 3},
+		{http.StatusInternalServerError, true, 3},
+		{http.StatusBadGateway, true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			a := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			})
+			for range 3 {
+				a.enqueue(ResourcesSnapshot{})
+			}
+			err := a.flush()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("flush error = %v, want error: %v", err, tt.wantErr)
+			}
+			if len(a.buffer) != tt.buffered {
+				t.Errorf("buffered = %d, want %d", len(a.buffer), tt.buffered)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/tomek7667/links/internal/domain"
+	"github.com/tomek7667/links/pkg/api"
+)
+
+// Error codes of ErrorResponse. Clients should branch on these rather than
+// on the message.
+const (
+	codeInvalidBody      = "invalid_body"
+	codeValidationFailed = "validation_failed"
+	codeInvalidParameter = "invalid_parameter"
+	codeLimitExceeded    = "limit_exceeded"
+	codeBodyTooLarge     = "body_too_large"
+	codeRateLimited      = "rate_limited"
+	codeUnauthorized     = "unauthorized"
+	codeNotFound         = "not_found"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeUnavailable      = "unavailable"
+	codeInternal         = "internal"
+)
+
+type (
+	ErrorResponse = api.ErrorResponse
+	APIError      = api.APIError
+)
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeAPIError(w, status, APIError{Code: code, Message: message})
+}
+
+func writeValidationError(w http.ResponseWriter, err *domain.ValidationError) {
+	writeAPIError(w, http.StatusBadRequest, APIError{
+		Code:    codeValidationFailed,
+		Message: err.Error(),
+		Fields:  err.Fields,
+	})
+}
+
+func writeAPIError(w http.ResponseWriter, status int, e APIError) {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json; charset=utf-8")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Error: e})
+}
+
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, codeNotFound, "no route for "+r.URL.Path)
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+}
@@ -0,0 +1,178 @@
+package http
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Helpers shared by the tests that read testdata/ or lay out a fake sysfs
+// or cgroup tree in a temporary directory.
+
+func readFixture(t *testing.T, path ...string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(append([]string{"testdata"}, path...)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func ptrValue[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func writeSysfsFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(link), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type fakeHwmonChip struct {
+	device string // below devices/, "" for a virtual chip
+	files  map[string]string
+}
+
+// fakeSysfs lays chips out like the kernel does: the hwmon node lives below
+// its device, class/hwmon/hwmonN links to it and hwmonN/device links back
+// to the device. Chips are numbered in the given order.
+func fakeSysfs(t *testing.T, chips []fakeHwmonChip) string {
+	t.Helper()
+	root := t.TempDir()
+	for i, c := range chips {
+		name := "hwmon" + string(rune('0'+i))
+		parent := filepath.Join(root, "devices", "virtual", "hwmon")
+		if c.device != "" {
+			parent = filepath.Join(root, "devices", c.device, "hwmon")
+		}
+		dir := filepath.Join(parent, name)
+		writeSysfsFiles(t, dir, c.files)
+		if c.device != "" {
+			symlink(t, "../..", filepath.Join(dir, "device"))
+		}
+		rel, err := filepath.Rel(filepath.Join(root, "class", "hwmon"), dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		symlink(t, rel, filepath.Join(root, "class", "hwmon", name))
+	}
+
+	writeSysfsFiles(t, filepath.Join(root, "class", "thermal", "thermal_zone0"), map[string]string{
+		"type": "x86_pkg_temp",
+		"temp": "45000",
+	})
+	writeSysfsFiles(t, filepath.Join(root, "class", "thermal", "thermal_zone1"), map[string]string{
+		"type": "acpitz",
+		"temp": "0",
+	})
+	writeSysfsFiles(t, filepath.Join(root, "class", "thermal", "cooling_device0"), map[string]string{
+		"type": "Processor",
+	})
+	return root
+}
+
+// fakeDRMCard lays a card out like the kernel: the device directory lives
+// below devices/, class/drm/cardN holds the card attributes and links back
+// to it through "device".
+func fakeDRMCard(t *testing.T, root, card, pciPath string, cardFiles map[string]map[string]string, devFiles map[string]map[string]string) {
+	t.Helper()
+	devDir := filepath.Join(root, "devices", pciPath)
+	cardDir := filepath.Join(root, "class", "drm", card)
+	for dir, files := range devFiles {
+		writeSysfsFiles(t, filepath.Join(devDir, dir), files)
+	}
+	for dir, files := range cardFiles {
+		writeSysfsFiles(t, filepath.Join(cardDir, dir), files)
+	}
+	rel, err := filepath.Rel(cardDir, devDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	symlink(t, rel, filepath.Join(cardDir, "device"))
+}
+
+func fakeDRMSysfs(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	fakeDRMCard(t, root, "card0", "pci0000:00/0000:00:03.1/0000:03:00.0", nil, map[string]map[string]string{
+		".": {
+			"uevent":              "DRIVER=amdgpu\nPCI_CLASS=30000\nPCI_SLOT_NAME=0000:03:00.0",
+			"gpu_busy_percent":    "37",
+			"mem_info_vram_used":  "1073741824",
+			"mem_info_vram_total": "8589934592",
+		},
+		"hwmon/hwmon3": {
+			"temp1_input":    "54000",
+			"power1_average": "42000000",
+			"freq1_input":    "1850000000",
+		},
+	})
+	// Kernels before 6.2: rps files directly on the card.
+	fakeDRMCard(t, root, "card1", "pci0000:00/0000:00:02.0", map[string]map[string]string{
+		".":     {"gt_act_freq_mhz": "350", "gt_max_freq_mhz": "1300"},
+		"power": {"rc6_residency_ms": "120000"},
+	}, map[string]map[string]string{
+		".":            {"uevent": "DRIVER=i915\nPCI_SLOT_NAME=0000:00:02.0"},
+		"hwmon/hwmon5": {"power1_input": "3500000"},
+	})
+	// Multi-GT i915 with the files under gt/gt0.
+	fakeDRMCard(t, root, "card2", "pci0000:00/0000:00:1c.4/0000:05:00.0", map[string]map[string]string{
+		"gt/gt0": {"rps_act_freq_mhz": "0", "rps_max_freq_mhz": "2400", "rc6_residency_ms": "987654"},
+	}, map[string]map[string]string{
+		".": {"uevent": "DRIVER=i915\nPCI_SLOT_NAME=0000:05:00.0"},
+	})
+	fakeDRMCard(t, root, "card3", "pci0000:00/0000:00:1d.0/0000:07:00.0", nil, map[string]map[string]string{
+		".":                {"uevent": "DRIVER=xe\nPCI_SLOT_NAME=0000:07:00.0"},
+		"tile0/gt0/freq0":  {"act_freq": "1550", "max_freq": "2050"},
+		"tile0/gt0/gtidle": {"idle_residency_ms": "4242"},
+		"hwmon/hwmon7":     {"temp1_input": "61500"},
+		"tile0/gt1/freq0":  {"act_freq": "900"},
+		"tile0/gt1/gtidle": {"idle_residency_ms": "1"},
+	})
+	// Unsupported drivers and connector nodes are skipped.
+	fakeDRMCard(t, root, "card4", "pci0000:00/0000:00:04.0", nil, map[string]map[string]string{
+		".": {"uevent": "DRIVER=virtio-pci\nPCI_SLOT_NAME=0000:00:04.0"},
+	})
+	writeSysfsFiles(t, filepath.Join(root, "class", "drm", "card0-DP-1"), map[string]string{"status": "connected"})
+	writeSysfsFiles(t, filepath.Join(root, "class", "drm", "renderD128"), map[string]string{"dev": "226:128"})
+	return root
+}
+
+// useCgroupFixture copies testdata/cgroup/<name> to a temporary root, so the
+// test can update counters between samples, and points cgroupFSRoot at it.
+func useCgroupFixture(t *testing.T, name string) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.CopyFS(root, os.DirFS(filepath.Join("testdata", "cgroup", name))); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("container", "")
+	old := cgroupFSRoot
+	cgroupFSRoot = root
+	t.Cleanup(func() { cgroupFSRoot = old })
+	return root
+}
+
+func approx(got *float64, want float64) bool {
+	return got != nil && math.Abs(*got-want) < 1e-9
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/tomek7667/links/pkg/api"
+)
+
+type (
+	BuildInfo   = api.BuildInfo
+	VersionInfo = api.VersionInfo
+	Readiness   = api.Readiness
+)
+
+func (s *Server) readiness() Readiness {
+	// New gets an already loaded store, so that check always passes.
+	checks := map[string]string{
+		"store":     "ok",
+		"resources": "ok",
+		"listeners": "ok",
+	}
+	if s.resources.Snapshot(false).UpdatedAt == 0 {
+		checks["resources"] = "no snapshot yet"
+	}
+	if !s.serving.Load() {
+		checks["listeners"] = "not serving"
+	}
+	ready := true
+	for _, v := range checks {
+		if v != "ok" {
+			ready = false
+		}
+	}
+	return Readiness{Ready: ready, Checks: checks}
+}
+
+// AddHealthRoutes registers /healthz (the process answers), /readyz (it can
+// serve the dashboard) and /api/version. With a BasePath, handler also
+// serves the first two at the root.
+func (s *Server) AddHealthRoutes() {
+	s.r.Get("/healthz", s.healthz)
+	s.r.Get("/readyz", s.readyz)
+
+	s.read.Get("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		info := VersionInfo{
+			BuildInfo:     s.opts.Build,
+			StartedAt:     s.startedAt.UnixMilli(),
+			UptimeSeconds: int64(time.Since(s.startedAt) / time.Second),
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.NewEncoder(w).Encode(info); err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+			return
+		}
+	})
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	rd := s.readiness()
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if !rd.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(rd)
+}
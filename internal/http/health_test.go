@@ -0,0 +1,60 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthRoutesOutsideBasePath(t *testing.T) {
+	s := New(0, nil, Options{BasePath: "/links"})
+	s.addRoutes()
+
+	for _, tt := range []struct {
+		target string
+		want   int
+	}{
+		{"/links/healthz", http.StatusOK},
+		{"/healthz", http.StatusOK},
+		// Not serving and no snapshot yet.
+		{"/links/readyz", http.StatusServiceUnavailable},
+		{"/readyz", http.StatusServiceUnavailable},
+		// Only the health checks are mounted at the root.
+		{"/api/version", http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		s.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if rec.Code != tt.want {
+			t.Errorf("GET %s = %d, want %d", tt.target, rec.Code, tt.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var rd Readiness
+	if err := json.Unmarshal(rec.Body.Bytes(), &rd); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"store": "ok", "resources": "no snapshot yet", "listeners": "not serving"}
+	for k, v := range want {
+		if rd.Checks[k] != v {
+			t.Errorf("check %s = %q, want %q", k, rd.Checks[k], v)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/links/api/openapi.json", nil))
+	var doc struct {
+		Paths map[string]struct {
+			Servers []any `json:"servers"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	servers := doc.Paths["/readyz"].Servers
+	if len(servers) != 2 {
+		t.Errorf("/readyz servers = %v, want the base path and the root", servers)
+	}
+}
@@ -2,53 +2,124 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/tomek7667/links/internal/domain"
 )
 
+type indexData struct {
+	BasePath string
+	Links    []domain.Link
+}
+
 func (s *Server) AddIndexRoute() {
 	s.r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-		links := s.dber.GetLinks()
+		data := indexData{
+			BasePath: s.opts.BasePath,
+			Links:    s.dber.GetLinks(),
+		}
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		if err := indexTmpl.Execute(w, links); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if err := indexTmpl.Execute(w, data); err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+			return
+		}
+	})
+
+	s.read.Get("/api/links", func(w http.ResponseWriter, r *http.Request) {
+		links := s.dber.GetLinks()
+		if links == nil {
+			links = []domain.Link{}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.NewEncoder(w).Encode(links); err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 			return
 		}
 	})
 
-	s.r.Post("/api/links", func(w http.ResponseWriter, r *http.Request) {
+	s.write.Post("/api/links", func(w http.ResponseWriter, r *http.Request) {
 		var link domain.Link
-		if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		if !decodeJSONBody(w, r, &link) {
+			return
+		}
+		// The store validates and normalises the link; see
+		// api.NormalizeLink.
+		if err := s.dber.SaveLink(link); err != nil {
+			var invalid *domain.ValidationError
+			switch {
+			case errors.As(err, &invalid):
+				writeValidationError(w, invalid)
+			case errors.Is(err, domain.ErrLimitExceeded):
+				writeError(w, http.StatusUnprocessableEntity, codeLimitExceeded, err.Error())
+			default:
+				writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+			}
 			return
 		}
-		s.dber.SaveLink(link)
 		w.WriteHeader(http.StatusCreated)
 	})
 
-	s.r.Delete("/api/links", func(w http.ResponseWriter, r *http.Request) {
+	s.write.Delete("/api/links", func(w http.ResponseWriter, r *http.Request) {
 		var req struct {
 			Url string `json:"url"`
 		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		if !decodeJSONBody(w, r, &req) {
+			return
+		}
+		if strings.TrimSpace(req.Url) == "" {
+			writeValidationError(w, &domain.ValidationError{Fields: []domain.FieldError{{Field: "url", Message: "is required"}}})
 			return
 		}
 		s.dber.DeleteLink(req.Url)
 		w.WriteHeader(http.StatusOK)
 	})
 
-	s.r.Get("/api/resources", func(w http.ResponseWriter, r *http.Request) {
+	s.read.Get("/api/resources", func(w http.ResponseWriter, r *http.Request) {
 		if s.resources == nil {
-			http.Error(w, "resources not available", http.StatusServiceUnavailable)
+			writeError(w, http.StatusServiceUnavailable, codeUnavailable, "resource monitor not running")
 			return
 		}
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.Header().Set("Cache-Control", "no-store")
 		withHistory := r.URL.Query().Get("history") == "1"
-		if err := json.NewEncoder(w).Encode(s.resources.Snapshot(withHistory)); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		snap := s.resources.Snapshot(withHistory)
+		snap.Containers = withoutSavedLinks(snap.Containers, s.dber.GetLinks())
+		if err := json.NewEncoder(w).Encode(snap); err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+			return
+		}
+	})
+
+	s.read.Get("/api/processes", func(w http.ResponseWriter, r *http.Request) {
+		if s.resources == nil {
+			writeError(w, http.StatusServiceUnavailable, codeUnavailable, "resource monitor not running")
+			return
+		}
+		qs := r.URL.Query()
+		q := ProcessQuery{
+			Sort:   qs.Get("sort"),
+			Desc:   qs.Get("order") != "asc",
+			Filter: qs.Get("q"),
+			User:   qs.Get("user"),
+			State:  qs.Get("state"),
+		}
+		if v := qs.Get("limit"); v != "" {
+			limit, err := strconv.Atoi(v)
+			if err != nil || limit < 0 {
+				writeError(w, http.StatusBadRequest, codeInvalidParameter, "limit must be a non-negative integer")
+				return
+			}
+			q.Limit = limit
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		withHistory := qs.Get("history") == "1"
+		if err := json.NewEncoder(w).Encode(s.resources.Processes(q, withHistory)); err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 			return
 		}
 	})
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tomek7667/links/internal/domain"
+	"github.com/tomek7667/links/pkg/api"
+)
+
+const (
+	ingestMaxBatch = 1000
+	agentStaleTTL  = 24 * time.Hour
+)
+
+type (
+	IngestBatch = api.IngestBatch
+	AgentStatus = api.AgentStatus
+)
+
+type agentRegistry struct {
+	mu     sync.RWMutex
+	agents map[string]*AgentStatus
+}
+
+func newAgentRegistry() *agentRegistry {
+	return &agentRegistry{agents: make(map[string]*AgentStatus)}
+}
+
+func (r *agentRegistry) ingest(batch IngestBatch, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a := r.agents[batch.Agent]
+	if a == nil {
+		a = &AgentStatus{Name: batch.Agent}
+		r.agents[batch.Agent] = a
+	}
+	for _, snap := range batch.Snapshots {
+		// Replayed batches may arrive out of order; keep the newest snapshot.
+		if snap.UpdatedAt >= a.Snapshot.UpdatedAt {
+			a.Snapshot = snap
+		}
+	}
+	a.Snapshot.History = nil
+	a.Received += len(batch.Snapshots)
+	a.LastSeenAt = now.UnixMilli()
+
+	cutoff := now.Add(-agentStaleTTL).UnixMilli()
+	for name, st := range r.agents {
+		if st.LastSeenAt < cutoff {
+			delete(r.agents, name)
+		}
+	}
+}
+
+func (r *agentRegistry) list() []AgentStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]AgentStatus, 0, len(r.agents))
+	for _, a := range r.agents {
+		out = append(out, *a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (s *Server) AddIngestRoutes() {
+	s.ingest.Post("/api/ingest", func(w http.ResponseWriter, r *http.Request) {
+		if s.opts.IngestToken == "" {
+			writeError(w, http.StatusNotFound, codeNotFound, "ingest is disabled on this server")
+			return
+		}
+		token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.IngestToken)) != 1 {
+			writeError(w, http.StatusUnauthorized, codeUnauthorized, "invalid token")
+			return
+		}
+
+		var batch IngestBatch
+		if !decodeJSONBody(w, r, &batch) {
+			return
+		}
+		batch.Agent = strings.TrimSpace(batch.Agent)
+		if batch.Agent == "" {
+			writeValidationError(w, &domain.ValidationError{Fields: []domain.FieldError{{Field: "agent", Message: "is required"}}})
+			return
+		}
+		if len(batch.Snapshots) > ingestMaxBatch {
+			writeError(w, http.StatusRequestEntityTooLarge, codeBodyTooLarge, fmt.Sprintf("at most %d snapshots per batch", ingestMaxBatch))
+			return
+		}
+		// Agents behind one NAT or proxy share an address, so each agent
+		// gets its own bucket.
+		if s.ingestLimit != nil {
+			if ok, wait := s.ingestLimit.allow("agent:"+batch.Agent, time.Now()); !ok {
+				writeRateLimited(w, wait)
+				return
+			}
+		}
+
+		s.agents.ingest(batch, time.Now())
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	s.read.Get("/api/agents", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.NewEncoder(w).Encode(s.agents.list()); err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+			return
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const defaultUnixSocketMode = 0o660
+
+// systemd passes activated sockets starting at this descriptor. Tests move
+// it out of the way of the runtime's own descriptors.
+var listenFDsStart = 3
+
+// listener is a bound socket plus whether it should speak TLS when TLS is
+// configured; unix sockets stay plain since a local reverse proxy
+// terminates TLS in front of them.
+type listener struct {
+	net.Listener
+	name string
+	tls  bool
+}
+
+// listeners binds every --listen address, falling back to systemd socket
+// activation and then to ":<port>". Accepted forms:
+//
+//	:8080, 127.0.0.1:8080, [::1]:8080
+//	unix:/run/linksserver.sock
+//	systemd            all sockets passed via LISTEN_FDS
+//	systemd:NAME       sockets whose FileDescriptorName= is NAME
+func (s *Server) listeners() ([]listener, error) {
+	addrs := s.opts.Listen
+	if len(addrs) == 0 {
+		if os.Getenv("LISTEN_FDS") != "" {
+			addrs = []string{"systemd"}
+		} else {
+			addrs = []string{fmt.Sprintf(":%d", s.port)}
+		}
+	}
+
+	var activated []listener
+	var out []listener
+	closeAll := func() {
+		for _, l := range out {
+			_ = l.Close()
+		}
+		for _, l := range activated {
+			_ = l.Close()
+		}
+	}
+
+	for _, addr := range addrs {
+		addr = strings.TrimSpace(addr)
+		switch {
+		case addr == "systemd" || strings.HasPrefix(addr, "systemd:"):
+			if activated == nil {
+				var err error
+				if activated, err = systemdListeners(); err != nil {
+					closeAll()
+					return nil, err
+				}
+			}
+			name := strings.TrimPrefix(strings.TrimPrefix(addr, "systemd"), ":")
+			found := false
+			for i := 0; i < len(activated); i++ {
+				if name != "" && activated[i].name != "systemd:"+name {
+					continue
+				}
+				out = append(out, activated[i])
+				activated = append(activated[:i], activated[i+1:]...)
+				i--
+				found = true
+			}
+			if !found {
+				closeAll()
+				return nil, fmt.Errorf("no socket-activated listener for '%s'", addr)
+			}
+
+		case strings.HasPrefix(addr, "unix:"):
+			l, err := listenUnix(strings.TrimPrefix(addr, "unix:"), s.unixSocketMode())
+			if err != nil {
+				closeAll()
+				return nil, err
+			}
+			out = append(out, l)
+
+		default:
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				closeAll()
+				if errors.Is(err, syscall.EACCES) {
+					return nil, fmt.Errorf("failed to listen on %s: %w (use a port above 1024, run with CAP_NET_BIND_SERVICE or use systemd socket activation)", addr, err)
+				}
+				return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+			}
+			out = append(out, listener{Listener: ln, name: addr, tls: true})
+		}
+	}
+	// Sockets systemd passed that no --listen asked for.
+	for _, l := range activated {
+		_ = l.Close()
+	}
+	return out, nil
+}
+
+func (s *Server) unixSocketMode() os.FileMode {
+	if s.opts.UnixSocketMode != 0 {
+		return s.opts.UnixSocketMode
+	}
+	return defaultUnixSocketMode
+}
+
+func listenUnix(path string, mode os.FileMode) (listener, error) {
+	if path == "" {
+		return listener{}, fmt.Errorf("unix socket path is empty")
+	}
+	// A socket left behind by an unclean exit makes bind fail; only remove it
+	// when nothing is accepting on it anymore.
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			_ = conn.Close()
+			return listener{}, fmt.Errorf("unix socket '%s' is already in use", path)
+		}
+		_ = os.Remove(path)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return listener{}, fmt.Errorf("failed to listen on unix:%s: %w", path, err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		_ = ln.Close()
+		return listener{}, fmt.Errorf("failed to set mode of '%s': %w", path, err)
+	}
+	return listener{Listener: ln, name: "unix:" + path}, nil
+}
+
+// systemdListeners takes over the sockets passed by systemd socket
+// activation (sd_listen_fds). The variables are cleared so that child
+// processes don't think the sockets are theirs.
+func systemdListeners() ([]listener, error) {
+	defer func() {
+		_ = os.Unsetenv("LISTEN_PID")
+		_ = os.Unsetenv("LISTEN_FDS")
+		_ = os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, fmt.Errorf("no sockets passed by systemd (LISTEN_PID is not this process)")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("no sockets passed by systemd (LISTEN_FDS=%q)", os.Getenv("LISTEN_FDS"))
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	out := make([]listener, 0, n)
+	for i := 0; i < n; i++ {
+		name := "systemd"
+		if i < len(names) && names[i] != "" {
+			name = "systemd:" + names[i]
+		}
+		f := os.NewFile(uintptr(listenFDsStart+i), name)
+		ln, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			for _, l := range out {
+				_ = l.Close()
+			}
+			return nil, fmt.Errorf("socket-activated fd %d: %w", listenFDsStart+i, err)
+		}
+		_, isUnix := ln.(*net.UnixListener)
+		out = append(out, listener{Listener: ln, name: name, tls: !isUnix})
+	}
+	return out, nil
+}
+
+// httpsPort is the port the redirect listener points clients at.
+func httpsPort(listeners []listener, fallback int) int {
+	for _, l := range listeners {
+		if addr, ok := l.Addr().(*net.TCPAddr); ok && l.tls {
+			return addr.Port
+		}
+	}
+	return fallback
+}
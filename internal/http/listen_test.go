@@ -0,0 +1,141 @@
+//go:build linux
+
+package http
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// passSockets opens a TCP and a unix listener and moves them to the
+// descriptors systemd would use, starting at listenFDsStart.
+func passSockets(t *testing.T) {
+	t.Helper()
+	old := listenFDsStart
+	listenFDsStart = 900
+	t.Cleanup(func() { listenFDsStart = old })
+
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unix, err := net.Listen("unix", filepath.Join(t.TempDir(), "links.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, l := range []interface{ File() (*os.File, error) }{tcp.(*net.TCPListener), unix.(*net.UnixListener)} {
+		f, err := l.File()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd := listenFDsStart + i
+		if err := syscall.Dup3(int(f.Fd()), fd, 0); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		// systemdListeners closes the ones it takes over.
+		t.Cleanup(func() { _ = syscall.Close(fd) })
+	}
+	tcp.Close()
+	unix.Close()
+}
+
+func TestListenersSystemd(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	tests := []struct {
+		name   string
+		env    map[string]string
+		listen []string
+		// want is the name and tls flag of each listener, e.g. "systemd:web tls".
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "unset falls back to the port",
+			want: []string{":0 tls"},
+		},
+		{
+			name:    "pid mismatch",
+			env:     map[string]string{"LISTEN_PID": "1", "LISTEN_FDS": "2"},
+			wantErr: "LISTEN_PID is not this process",
+		},
+		{
+			name:    "no fds",
+			env:     map[string]string{"LISTEN_PID": pid, "LISTEN_FDS": "0"},
+			wantErr: `LISTEN_FDS="0"`,
+		},
+		{
+			name:    "more fds than passed",
+			env:     map[string]string{"LISTEN_PID": pid, "LISTEN_FDS": "3"},
+			wantErr: "socket-activated fd 902",
+		},
+		{
+			name: "unnamed",
+			env:  map[string]string{"LISTEN_PID": pid, "LISTEN_FDS": "2"},
+			want: []string{"systemd tls", "systemd"},
+		},
+		{
+			name: "fewer names than fds",
+			env:  map[string]string{"LISTEN_PID": pid, "LISTEN_FDS": "2", "LISTEN_FDNAMES": "web"},
+			want: []string{"systemd:web tls", "systemd"},
+		},
+		{
+			name:   "by name",
+			env:    map[string]string{"LISTEN_PID": pid, "LISTEN_FDS": "2", "LISTEN_FDNAMES": "web:local"},
+			listen: []string{"systemd:local"},
+			want:   []string{"systemd:local"},
+		},
+		{
+			name:    "unknown name",
+			env:     map[string]string{"LISTEN_PID": pid, "LISTEN_FDS": "2", "LISTEN_FDNAMES": "web:local"},
+			listen:  []string{"systemd:admin"},
+			wantErr: "no socket-activated listener for 'systemd:admin'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+				t.Setenv(k, tt.env[k])
+				if tt.env[k] == "" {
+					os.Unsetenv(k)
+				}
+			}
+			passSockets(t)
+
+			s := New(0, nil, Options{Listen: tt.listen})
+			got, err := s.listeners()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, l := range got {
+				name := l.name
+				if l.tls {
+					name += " tls"
+				}
+				names = append(names, name)
+				l.Close()
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("listeners = %q, want %q", names, tt.want)
+			}
+			for _, k := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+				if _, ok := os.LookupEnv(k); ok && tt.env != nil {
+					t.Errorf("%s is still set", k)
+				}
+			}
+		})
+	}
+}
@@ -1,46 +1,62 @@
 package http
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-func newRequestLogger(ignoredPaths ...string) func(next http.Handler) http.Handler {
-	ignored := make(map[string]struct{}, len(ignoredPaths))
-	for _, p := range ignoredPaths {
-		ignored[p] = struct{}{}
+// DefaultLogIgnorePaths are polled by the dashboard every second, or by
+// healthchecks, and would drown out everything else at info level.
+var DefaultLogIgnorePaths = []string{"/api/resources", "/api/processes", "/api/agents", "/healthz", "/readyz"}
+
+// newRequestLogger logs one record per request with the chi request ID.
+// Paths matching ignoredPaths (globs relative to basePath, see
+// matchAnyGlob) are only logged at debug level. Every request also goes to
+// the access log when one is configured.
+func newRequestLogger(basePath string, ignoredPaths []string, access *accessLogger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				elapsed := time.Since(start)
+				status := ww.Status()
+				if status == 0 {
+					// Nothing written, net/http sends 200.
+					status = http.StatusOK
+				}
+				if access != nil {
+					access.log(r, status, ww.BytesWritten(), elapsed, start)
+				}
+
+				level := slog.LevelInfo
+				switch {
+				case status >= 500:
+					level = slog.LevelError
+				case matchAnyGlob(ignoredPaths, strings.TrimPrefix(r.URL.Path, basePath)):
+					level = slog.LevelDebug
+				}
+				ctx := r.Context()
+				if !slog.Default().Enabled(ctx, level) {
+					return
+				}
+				slog.LogAttrs(ctx, level, "request",
+					slog.String("request_id", middleware.GetReqID(ctx)),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("query", r.URL.RawQuery),
+					slog.Int("status", status),
+					slog.Int("bytes", ww.BytesWritten()),
+					slog.Duration("duration", elapsed),
+					slog.String("remote", r.RemoteAddr),
+					slog.String("proto", r.Proto),
+				)
+			}()
+			next.ServeHTTP(ww, r)
+		})
 	}
-
-	base := &middleware.DefaultLogFormatter{
-		Logger:  log.New(os.Stdout, "", log.LstdFlags),
-		NoColor: false,
-	}
-
-	return middleware.RequestLogger(&selectiveLogFormatter{
-		ignoredPaths: ignored,
-		base:         base,
-	})
-}
-
-type selectiveLogFormatter struct {
-	ignoredPaths map[string]struct{}
-	base         middleware.LogFormatter
-}
-
-func (f *selectiveLogFormatter) NewLogEntry(r *http.Request) middleware.LogEntry {
-	if _, ok := f.ignoredPaths[r.URL.Path]; ok {
-		return noopLogEntry{}
-	}
-	return f.base.NewLogEntry(r)
-}
-
-type noopLogEntry struct{}
-
-func (noopLogEntry) Write(status, bytes int, header http.Header, elapsed time.Duration, extra interface{}) {
 }
-
-func (noopLogEntry) Panic(v interface{}, stack []byte) {}
@@ -0,0 +1,352 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/tomek7667/links/pkg/api"
+)
+
+// The schemas in the OpenAPI document are derived from the Go types the
+// handlers encode, so the document changes together with the JSON and can't
+// drift from it. Only the paths are written by hand.
+
+type openAPISchemas struct {
+	defs  map[string]any
+	names map[reflect.Type]string
+}
+
+// name is the components key for t: its package and type name, e.g.
+// "api.Link", or the full import path when two packages share a name.
+func (g *openAPISchemas) name(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := path.Base(t.PkgPath()) + "." + t.Name()
+	if _, taken := g.defs[name]; taken {
+		name = strings.ReplaceAll(t.PkgPath(), "/", ".") + "." + t.Name()
+	}
+	g.names[t] = name
+	return name
+}
+
+func (g *openAPISchemas) schema(t reflect.Type) map[string]any {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var s map[string]any
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.Name() == "" {
+			s = g.object(t)
+			break
+		}
+		name := g.name(t)
+		if _, ok := g.defs[name]; !ok {
+			// Reserve the name first so self-referencing types terminate.
+			g.defs[name] = nil
+			g.defs[name] = g.object(t)
+		}
+		s = map[string]any{"$ref": "#/components/schemas/" + name}
+		if nullable {
+			// $ref siblings are ignored in OpenAPI 3.0.
+			return map[string]any{"allOf": []any{s}, "nullable": true}
+		}
+		return s
+	case reflect.Slice, reflect.Array:
+		s = map[string]any{"type": "array", "items": g.schema(t.Elem())}
+		// nil slices encode as null.
+		nullable = true
+	case reflect.Map:
+		s = map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+		nullable = true
+	case reflect.String:
+		s = map[string]any{"type": "string"}
+	case reflect.Bool:
+		s = map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		s = map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		s = map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		s = map[string]any{"type": "number", "format": "float"}
+	case reflect.Float64:
+		s = map[string]any{"type": "number", "format": "double"}
+	default:
+		s = map[string]any{}
+	}
+	if t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64 {
+		s["minimum"] = 0
+	}
+	if nullable {
+		s["nullable"] = true
+	}
+	return s
+}
+
+func (g *openAPISchemas) object(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			// encoding/json promotes the fields of embedded structs.
+			embedded := g.object(f.Type)
+			for k, v := range embedded["properties"].(map[string]any) {
+				props[k] = v
+			}
+			if req, ok := embedded["required"].([]string); ok {
+				required = append(required, req...)
+			}
+			continue
+		}
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	obj := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		obj["required"] = required
+	}
+	return obj
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+func openAPIResponse(description string, schema map[string]any) map[string]any {
+	r := map[string]any{"description": description}
+	if schema != nil {
+		r["content"] = jsonContent(schema)
+	}
+	return r
+}
+
+func queryParam(name, typ, description string) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          "query",
+		"description": description,
+		"schema":      map[string]any{"type": typ},
+	}
+}
+
+// buildOpenAPI describes the routes registered by AddIndexRoute,
+// AddIngestRoutes, AddHealthRoutes and AddOpenAPIRoute.
+func buildOpenAPI(version, basePath string) map[string]any {
+	g := &openAPISchemas{defs: make(map[string]any), names: make(map[reflect.Type]string)}
+	emptyResp := func(description string) map[string]any {
+		return openAPIResponse(description, nil)
+	}
+	errorResponse := g.schema(reflect.TypeOf(ErrorResponse{}))
+	errResp := func(description string) map[string]any {
+		return openAPIResponse(description, errorResponse)
+	}
+	link := g.schema(reflect.TypeOf(api.Link{}))
+	deleteReq := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"url": map[string]any{"type": "string"}},
+		"required":   []string{"url"},
+	}
+	readiness := g.schema(reflect.TypeOf(Readiness{}))
+	history := queryParam("history", "string", `"1" includes the graph history`)
+
+	paths := map[string]any{
+		"/api/links": map[string]any{
+			"get": map[string]any{
+				"operationId": "listLinks",
+				"summary":     "List saved links",
+				"responses": map[string]any{
+					"200": openAPIResponse("Saved links", map[string]any{"type": "array", "items": link}),
+				},
+			},
+			"post": map[string]any{
+				"operationId": "saveLink",
+				"summary":     "Add a link, or replace the one with the same url",
+				"requestBody": map[string]any{"required": true, "content": jsonContent(link)},
+				"responses": map[string]any{
+					"201": emptyResp("Saved"),
+					"400": errResp("Invalid JSON (invalid_body) or link (validation_failed, see fields)"),
+					"413": errResp("Body too large"),
+					"422": errResp("Too many links or a field too long"),
+				},
+			},
+			"delete": map[string]any{
+				"operationId": "deleteLink",
+				"summary":     "Delete the link with the given url",
+				"requestBody": map[string]any{"required": true, "content": jsonContent(deleteReq)},
+				"responses": map[string]any{
+					"200": emptyResp("Deleted (or not present)"),
+					"400": errResp("Invalid JSON or missing url"),
+					"413": errResp("Body too large"),
+				},
+			},
+		},
+		"/api/resources": map[string]any{
+			"get": map[string]any{
+				"operationId": "getResources",
+				"summary":     "Current resource snapshot",
+				"parameters":  []any{history},
+				"responses": map[string]any{
+					"200": openAPIResponse("Snapshot", g.schema(reflect.TypeOf(ResourcesSnapshot{}))),
+					"503": errResp("Resource monitor not running"),
+				},
+			},
+		},
+		"/api/processes": map[string]any{
+			"get": map[string]any{
+				"operationId": "listProcesses",
+				"summary":     "Process table",
+				"parameters": []any{
+					queryParam("sort", "string", "cpu (default), memory, pid, name, user, threads, start or state"),
+					queryParam("order", "string", `"asc" or "desc" (default)`),
+					queryParam("q", "string", "filter by name, command line or user"),
+					queryParam("user", "string", "only processes of this user"),
+					queryParam("state", "string", "only processes in this state"),
+					queryParam("limit", "integer", "maximum number of rows"),
+					history,
+				},
+				"responses": map[string]any{
+					"200": openAPIResponse("Processes", g.schema(reflect.TypeOf(ProcessList{}))),
+					"400": errResp("Invalid limit"),
+					"503": errResp("Resource monitor not running"),
+				},
+			},
+		},
+		"/api/ingest": map[string]any{
+			"post": map[string]any{
+				"operationId": "ingest",
+				"summary":     "Push snapshots from an agent",
+				"security":    []any{map[string]any{"ingestToken": []any{}}},
+				"requestBody": map[string]any{"required": true, "content": jsonContent(g.schema(reflect.TypeOf(IngestBatch{})))},
+				"responses": map[string]any{
+					"204": emptyResp("Accepted"),
+					"400": errResp("Invalid JSON or missing agent name"),
+					"401": errResp("Invalid token"),
+					"404": errResp("Ingest disabled on this server"),
+					"413": errResp("Body too large or too many snapshots in one batch"),
+				},
+			},
+		},
+		"/api/agents": map[string]any{
+			"get": map[string]any{
+				"operationId": "listAgents",
+				"summary":     "Agents that pushed snapshots in the last 24 hours",
+				"responses": map[string]any{
+					"200": openAPIResponse("Agents", map[string]any{"type": "array", "items": g.schema(reflect.TypeOf(AgentStatus{}))}),
+				},
+			},
+		},
+		"/api/version": map[string]any{
+			"get": map[string]any{
+				"operationId": "getVersion",
+				"summary":     "Build information and uptime",
+				"responses": map[string]any{
+					"200": openAPIResponse("Version", g.schema(reflect.TypeOf(VersionInfo{}))),
+				},
+			},
+		},
+		"/healthz": map[string]any{
+			"get": map[string]any{
+				"operationId": "healthz",
+				"summary":     "Liveness: the process answers requests",
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "Alive",
+						"content":     map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}},
+					},
+				},
+			},
+		},
+		"/readyz": map[string]any{
+			"get": map[string]any{
+				"operationId": "readyz",
+				"summary":     "Readiness: database loaded, first resource snapshot taken, listeners up",
+				"responses": map[string]any{
+					"200": openAPIResponse("Ready", readiness),
+					"503": openAPIResponse("Not ready", readiness),
+				},
+			},
+		},
+		"/api/openapi.json": map[string]any{
+			"get": map[string]any{
+				"operationId": "getOpenAPI",
+				"summary":     "This document",
+				"responses": map[string]any{
+					"200": openAPIResponse("OpenAPI 3.0 document", map[string]any{"type": "object"}),
+				},
+			},
+		},
+	}
+
+	// Every /api/ route is rate limited.
+	for p, item := range paths {
+		if !strings.HasPrefix(p, "/api/") {
+			continue
+		}
+		for _, op := range item.(map[string]any) {
+			op.(map[string]any)["responses"].(map[string]any)["429"] = errResp("Rate limit exceeded, see Retry-After")
+		}
+	}
+
+	server := basePath
+	if server == "" {
+		server = "/"
+	} else {
+		// The health checks also answer at the root; see Server.handler.
+		for _, p := range []string{"/healthz", "/readyz"} {
+			paths[p].(map[string]any)["servers"] = []any{map[string]any{"url": basePath}, map[string]any{"url": "/"}}
+		}
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "linksserver",
+			"version": version,
+		},
+		"servers": []any{map[string]any{"url": server}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": g.defs,
+			"securitySchemes": map[string]any{
+				"ingestToken": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+func (s *Server) AddOpenAPIRoute() {
+	var (
+		once sync.Once
+		doc  []byte
+		err  error
+	)
+	s.read.Get("/api/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			doc, err = json.MarshalIndent(buildOpenAPI(s.opts.Build.Version, s.opts.BasePath), "", "  ")
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write(doc)
+	})
+}
@@ -0,0 +1,219 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/tomek7667/links/internal/domain"
+)
+
+// memDber keeps links in memory for handler tests.
+type memDber struct {
+	links []domain.Link
+}
+
+func (d *memDber) SaveLink(l domain.Link) error { d.links = append(d.links, l); return nil }
+func (d *memDber) GetLinks() []domain.Link      { return d.links }
+func (d *memDber) DeleteLink(string)            {}
+func (d *memDber) Close()                       {}
+
+func fetchOpenAPI(t *testing.T, s *Server) map[string]any {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/openapi.json = %d: %s", rec.Code, rec.Body)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	s := New(0, nil, Options{})
+	s.addRoutes()
+
+	var registered []string
+	err := chi.Walk(s.r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if route != "/" {
+			registered = append(registered, method+" "+route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var documented []string
+	for path, item := range fetchOpenAPI(t, s)["paths"].(map[string]any) {
+		for method := range item.(map[string]any) {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	slices.Sort(registered)
+	slices.Sort(documented)
+	for _, r := range registered {
+		if !slices.Contains(documented, r) {
+			t.Errorf("%s is registered but not in /api/openapi.json", r)
+		}
+	}
+	for _, d := range documented {
+		if !slices.Contains(registered, d) {
+			t.Errorf("%s is in /api/openapi.json but not registered", d)
+		}
+	}
+}
+
+func TestOpenAPIRefsResolve(t *testing.T) {
+	s := New(0, nil, Options{})
+	s.addRoutes()
+	doc := fetchOpenAPI(t, s)
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/components/schemas/")
+				if _, ok := schemas[name]; !ok {
+					t.Errorf("unresolved $ref %q", ref)
+				}
+			}
+			for _, e := range v {
+				walk(e)
+			}
+		case []any:
+			for _, e := range v {
+				walk(e)
+			}
+		}
+	}
+	walk(doc)
+}
+
+// checkSchema reports where v, decoded from a response, doesn't fit schema:
+// wrong types, missing required properties or properties the schema
+// doesn't list.
+func checkSchema(t *testing.T, at string, schema map[string]any, v any, defs map[string]any) {
+	t.Helper()
+	if ref, ok := schema["$ref"].(string); ok {
+		schema = defs[strings.TrimPrefix(ref, "#/components/schemas/")].(map[string]any)
+	}
+	if v == nil {
+		if schema["nullable"] != true {
+			t.Errorf("%s: null, but not nullable", at)
+		}
+		return
+	}
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, s := range all {
+			checkSchema(t, at, s.(map[string]any), v, defs)
+		}
+		return
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			t.Errorf("%s: %T, want an object", at, v)
+			return
+		}
+		props, _ := schema["properties"].(map[string]any)
+		extra, _ := schema["additionalProperties"].(map[string]any)
+		for k, e := range obj {
+			switch {
+			case props[k] != nil:
+				checkSchema(t, at+"."+k, props[k].(map[string]any), e, defs)
+			case extra != nil:
+				checkSchema(t, at+"."+k, extra, e, defs)
+			case props != nil:
+				t.Errorf("%s.%s: not in the schema", at, k)
+			}
+		}
+		req, _ := schema["required"].([]any)
+		for _, k := range req {
+			if _, ok := obj[k.(string)]; !ok {
+				t.Errorf("%s.%s: required, but missing", at, k)
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			t.Errorf("%s: %T, want an array", at, v)
+			return
+		}
+		for i, e := range arr {
+			checkSchema(t, fmt.Sprintf("%s[%d]", at, i), schema["items"].(map[string]any), e, defs)
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			t.Errorf("%s: %T, want a string", at, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			t.Errorf("%s: %T, want a boolean", at, v)
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			t.Errorf("%s: %T, want a number", at, v)
+		} else if schema["type"] == "integer" && n != float64(int64(n)) {
+			t.Errorf("%s: %v, want an integer", at, n)
+		}
+	}
+}
+
+func TestOpenAPIResponsesMatchHandlers(t *testing.T) {
+	s := New(0, &memDber{links: []domain.Link{{Title: "Grafana", Url: "http://grafana.lan", Unit: "grafana.service"}}}, Options{})
+	s.addRoutes()
+	// Two samples, so rates and per-process CPU are filled in.
+	s.resources.update()
+	s.resources.update()
+	s.agents.ingest(IngestBatch{Agent: "pi", Snapshots: []ResourcesSnapshot{s.resources.Snapshot(false)}}, time.Now())
+
+	doc := fetchOpenAPI(t, s)
+	paths := doc["paths"].(map[string]any)
+	defs := doc["components"].(map[string]any)["schemas"].(map[string]any)
+
+	for _, target := range []string{
+		"/api/links",
+		"/api/resources?history=1",
+		"/api/processes?limit=5&history=1",
+		"/api/processes?limit=x",
+		"/api/agents",
+		"/api/version",
+		"/readyz",
+	} {
+		t.Run(target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+			path, _, _ := strings.Cut(target, "?")
+			op := paths[path].(map[string]any)["get"].(map[string]any)
+			res, ok := op["responses"].(map[string]any)[fmt.Sprint(rec.Code)].(map[string]any)
+			if !ok {
+				t.Fatalf("status %d is not documented: %s", rec.Code, rec.Body)
+			}
+			schema := res["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+
+			var body any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			checkSchema(t, "body", schema, body, defs)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"path"
+	"strings"
+)
+
+// TrustedProxies lists the peers whose X-Forwarded-For / X-Real-IP headers
+// are believed. Requests from anyone else keep their socket address.
+type TrustedProxies struct {
+	prefixes []netip.Prefix
+	// unix trusts peers on unix sockets, i.e. a local reverse proxy.
+	unix bool
+}
+
+// ParseTrustedProxies accepts CIDRs ("10.0.0.0/8"), single addresses
+// ("127.0.0.1") and "unix" for connections over unix sockets.
+func ParseTrustedProxies(values []string) (TrustedProxies, error) {
+	var t TrustedProxies
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		switch {
+		case v == "":
+			continue
+		case v == "unix":
+			t.unix = true
+		case strings.Contains(v, "/"):
+			p, err := netip.ParsePrefix(v)
+			if err != nil {
+				return TrustedProxies{}, fmt.Errorf("invalid trusted proxy %q: %w", v, err)
+			}
+			t.prefixes = append(t.prefixes, p.Masked())
+		default:
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return TrustedProxies{}, fmt.Errorf("invalid trusted proxy %q: %w", v, err)
+			}
+			t.prefixes = append(t.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		}
+	}
+	return t, nil
+}
+
+func (t TrustedProxies) empty() bool {
+	return len(t.prefixes) == 0 && !t.unix
+}
+
+func (t TrustedProxies) containsAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range t.prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// trustsPeer reports whether remoteAddr (http.Request.RemoteAddr) is a
+// trusted proxy. Unix socket peers show up without a parseable address.
+func (t TrustedProxies) trustsPeer(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return t.unix
+	}
+	return t.containsAddr(addr)
+}
+
+// clientIP walks X-Forwarded-For from the right, skipping trusted proxies,
+// so a client can't spoof its address by sending the header itself.
+func (t TrustedProxies) clientIP(r *http.Request) (netip.Addr, bool) {
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		if i == 0 || !t.containsAddr(addr) {
+			return addr.Unmap(), true
+		}
+	}
+	if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return addr.Unmap(), true
+	}
+	return netip.Addr{}, false
+}
+
+// newRealIP replaces middleware.RealIP: RemoteAddr is only rewritten from
+// forwarding headers when the connection comes from a trusted proxy.
+func newRealIP(trusted TrustedProxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !trusted.empty() && trusted.trustsPeer(r.RemoteAddr) {
+				if addr, ok := trusted.clientIP(r); ok {
+					r.RemoteAddr = addr.String()
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// cleanBasePath turns "links/", "/links/" or "/links" into "/links"; the
+// root is "".
+func cleanBasePath(p string) string {
+	p = strings.TrimSpace(p)
+	if p == "" {
+		return ""
+	}
+	p = path.Clean("/" + p)
+	if p == "/" {
+		return ""
+	}
+	return p
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1", "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		trusted TrustedProxies
+		peer    string
+		xff     []string
+		realIP  string
+		want    string
+	}{
+		{
+			name: "no trusted proxies",
+			peer: "10.0.0.2:4711", xff: []string{"203.0.113.9"},
+			want: "10.0.0.2:4711",
+		},
+		{
+			name: "untrusted peer", trusted: trusted,
+			peer: "198.51.100.7:4711", xff: []string{"203.0.113.9"}, realIP: "203.0.113.10",
+			want: "198.51.100.7:4711",
+		},
+		{
+			name: "single hop", trusted: trusted,
+			peer: "10.0.0.2:4711", xff: []string{"203.0.113.9"},
+			want: "203.0.113.9",
+		},
+		{
+			// The client prepended a spoofed address; the right-most hop
+			// that isn't a trusted proxy is the one the proxies saw.
+			name: "multi-hop", trusted: trusted,
+			peer: "10.0.0.2:4711", xff: []string{"1.2.3.4, 203.0.113.9, 192.0.2.1", "10.0.0.3"},
+			want: "203.0.113.9",
+		},
+		{
+			name: "only trusted hops", trusted: trusted,
+			peer: "10.0.0.2:4711", xff: []string{"10.0.0.5, 10.0.0.3"},
+			want: "10.0.0.5",
+		},
+		{
+			name: "ipv4-mapped", trusted: trusted,
+			peer: "[::ffff:10.0.0.2]:4711", xff: []string{"::ffff:203.0.113.9"},
+			want: "203.0.113.9",
+		},
+		{
+			name: "malformed hop stops the walk", trusted: trusted,
+			peer: "10.0.0.2:4711", xff: []string{"203.0.113.9, not-an-ip"},
+			want: "10.0.0.2:4711",
+		},
+		{
+			name: "malformed hop falls back to X-Real-IP", trusted: trusted,
+			peer: "10.0.0.2:4711", xff: []string{"203.0.113.9, not-an-ip"}, realIP: "203.0.113.10",
+			want: "203.0.113.10",
+		},
+		{
+			name: "malformed X-Real-IP", trusted: trusted,
+			peer: "10.0.0.2:4711", realIP: "203.0.113.10:80",
+			want: "10.0.0.2:4711",
+		},
+		{
+			name: "unix peer", trusted: trusted,
+			peer: "@", xff: []string{"203.0.113.9"},
+			want: "203.0.113.9",
+		},
+		{
+			name: "unix peer not trusted", trusted: TrustedProxies{prefixes: trusted.prefixes},
+			peer: "@", xff: []string{"203.0.113.9"},
+			want: "@",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := newRealIP(tt.trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.peer
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
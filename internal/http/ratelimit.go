@@ -0,0 +1,231 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultMaxBodyBytes bounds link requests; DefaultMaxIngestBodyBytes
+	// leaves room for a full agent batch.
+	DefaultMaxBodyBytes       = 64 << 10
+	DefaultMaxIngestBodyBytes = 32 << 20
+
+	rateLimitSweepEvery = time.Minute
+)
+
+// RateLimit is a token bucket per client (per agent for ingest): Burst
+// requests at once, refilled at Rate per second. A zero Rate disables it.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// Limits throttle the API per route group: Read covers the GET /api/
+// endpoints, Write saving and deleting links and Ingest agent pushes.
+type Limits struct {
+	Read   RateLimit
+	Write  RateLimit
+	Ingest RateLimit
+
+	// MaxBodyBytes applies to link requests, MaxIngestBodyBytes to
+	// POST /api/ingest. Zero uses the defaults.
+	MaxBodyBytes       int64
+	MaxIngestBodyBytes int64
+}
+
+// ParseRateLimit reads "N/s", "N/m" or "N/h", optionally followed by
+// ":BURST" ("30/m:10"). The burst defaults to N. "0" or "off" disables the
+// limit.
+func ParseRateLimit(s string) (RateLimit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" || s == "off" {
+		return RateLimit{}, nil
+	}
+	spec, burstStr, hasBurst := strings.Cut(s, ":")
+	countStr, unit, ok := strings.Cut(spec, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected e.g. 10/s, 30/m or 30/m:10", s)
+	}
+	count, err := strconv.Atoi(countStr)
+	if err != nil || count <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: count must be a positive integer", s)
+	}
+	var per time.Duration
+	switch unit {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: unit must be s, m or h", s)
+	}
+	rl := RateLimit{Rate: float64(count) / per.Seconds(), Burst: count}
+	if hasBurst {
+		burst, err := strconv.Atoi(burstStr)
+		if err != nil || burst <= 0 {
+			return RateLimit{}, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", s)
+		}
+		rl.Burst = burst
+	}
+	return rl, nil
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type rateLimiter struct {
+	limit RateLimit
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &rateLimiter{limit: limit, buckets: make(map[string]*tokenBucket)}
+}
+
+// allow takes a token for key. When the bucket is empty it returns how long
+// until the next token.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	burst := float64(l.limit.Burst)
+	if now.Sub(l.lastSweep) >= rateLimitSweepEvery {
+		// Buckets that refilled completely are the same as new ones.
+		for k, b := range l.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*l.limit.Rate >= burst {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b := l.buckets[key]
+	if b == nil {
+		b = &tokenBucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*l.limit.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.limit.Rate * float64(time.Second))
+	return false, wait
+}
+
+type unixPeerKey struct{}
+
+// unixConnSeq numbers unix socket connections whose peer is unknown.
+var unixConnSeq atomic.Uint64
+
+// withUnixPeer is the http.Server ConnContext hook. Every request over a
+// unix socket has the RemoteAddr "@" (or ""), so the peer's identity is
+// kept in the context for rateLimitKey instead.
+func withUnixPeer(ctx context.Context, c net.Conn) context.Context {
+	uc, ok := c.(*net.UnixConn)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, unixPeerKey{}, unixPeerID(uc))
+}
+
+// unixConnID tells connections apart when peer credentials aren't
+// available.
+func unixConnID() string {
+	return "unix:conn=" + strconv.FormatUint(unixConnSeq.Add(1), 10)
+}
+
+// rateLimitKey is the client address without the port; newRealIP has
+// already resolved proxied clients. Unix socket peers are keyed by
+// withUnixPeer's identity.
+func rateLimitKey(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	if _, err := netip.ParseAddr(r.RemoteAddr); err != nil {
+		if peer, ok := r.Context().Value(unixPeerKey{}).(string); ok {
+			return peer
+		}
+	}
+	return r.RemoteAddr
+}
+
+func writeRateLimited(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	writeError(w, http.StatusTooManyRequests, codeRateLimited, "rate limit exceeded, retry later")
+}
+
+// newRateLimit answers 429 with Retry-After once a client runs out of
+// tokens. A nil limiter lets everything through.
+func newRateLimit(l *rateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, wait := l.allow(rateLimitKey(r), time.Now())
+			if !ok {
+				writeRateLimited(w, wait)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// newMaxBody caps request bodies; decodeJSONBody turns the overflow into a
+// 413.
+func newMaxBody(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				writeError(w, http.StatusRequestEntityTooLarge, codeBodyTooLarge, fmt.Sprintf("request body too large, limit is %d bytes", n))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// decodeJSONBody decodes r.Body into v and writes the error response
+// itself when that fails: 413 past the newMaxBody limit, 400 otherwise.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil {
+		return true
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, codeBodyTooLarge, fmt.Sprintf("request body too large, limit is %d bytes", tooLarge.Limit))
+		return false
+	}
+	writeError(w, http.StatusBadRequest, codeInvalidBody, "invalid JSON body: "+err.Error())
+	return false
+}
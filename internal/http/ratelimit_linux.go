@@ -0,0 +1,25 @@
+package http
+
+import (
+	"net"
+	"strconv"
+	"syscall"
+)
+
+// unixPeerID is the peer's user from SO_PEERCRED, so a local client can't
+// get a fresh bucket by reconnecting.
+func unixPeerID(c *net.UnixConn) string {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		return unixConnID()
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil || credErr != nil {
+		return unixConnID()
+	}
+	return "unix:uid=" + strconv.FormatUint(uint64(cred.Uid), 10)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package http
+
+import "net"
+
+// unixPeerID keys each connection separately where peer credentials aren't
+// read.
+func unixPeerID(*net.UnixConn) string {
+	return unixConnID()
+}
@@ -0,0 +1,178 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    RateLimit
+		wantErr bool
+	}{
+		{spec: "10/s", want: RateLimit{Rate: 10, Burst: 10}},
+		{spec: "60/m", want: RateLimit{Rate: 1, Burst: 60}},
+		{spec: "3600/h", want: RateLimit{Rate: 1, Burst: 3600}},
+		{spec: " 30/m:10 ", want: RateLimit{Rate: 0.5, Burst: 10}},
+		{spec: "", want: RateLimit{}},
+		{spec: "0", want: RateLimit{}},
+		{spec: "off", want: RateLimit{}},
+		{spec: "10", wantErr: true},
+		{spec: "10/d", wantErr: true},
+		{spec: "0/s", wantErr: true},
+		{spec: "-5/s", wantErr: true},
+		{spec: "x/s", wantErr: true},
+		{spec: "10/s:0", wantErr: true},
+		{spec: "10/s:", wantErr: true},
+		{spec: "10/s:-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRateLimit(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRateLimit(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRateLimit(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	if newRateLimiter(RateLimit{}) != nil {
+		t.Error("a zero rate should disable the limiter")
+	}
+
+	// 2 per second, burst 3.
+	l := newRateLimiter(RateLimit{Rate: 2, Burst: 3})
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	steps := []struct {
+		key  string
+		at   time.Duration
+		ok   bool
+		wait time.Duration
+	}{
+		{"a", 0, true, 0},
+		{"a", 0, true, 0},
+		{"a", 0, true, 0},
+		{"a", 0, false, 500 * time.Millisecond},
+		// Other clients have their own bucket.
+		{"b", 0, true, 0},
+		{"a", 250 * time.Millisecond, false, 250 * time.Millisecond},
+		{"a", 500 * time.Millisecond, true, 0},
+		{"a", 500 * time.Millisecond, false, 500 * time.Millisecond},
+		// A long pause refills up to the burst, not beyond.
+		{"a", time.Hour, true, 0},
+		{"a", time.Hour, true, 0},
+		{"a", time.Hour, true, 0},
+		{"a", time.Hour, false, 500 * time.Millisecond},
+	}
+	for i, s := range steps {
+		ok, wait := l.allow(s.key, at(s.at))
+		if ok != s.ok || wait != s.wait {
+			t.Errorf("step %d (%s at %v): allow = %v, %v, want %v, %v", i, s.key, s.at, ok, wait, s.ok, s.wait)
+		}
+	}
+
+	// The sweep drops buckets that are full again.
+	l.allow("c", at(time.Hour+rateLimitSweepEvery))
+	if len(l.buckets) != 1 {
+		t.Errorf("%d buckets after the sweep, want only c's", len(l.buckets))
+	}
+}
+
+func TestRateLimitKeyUnixPeers(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "links.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, rateLimitKey(r))
+		}),
+		ConnContext: withUnixPeer,
+	}
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	// A new connection per request, as a client dodging the limit would.
+	get := func() string {
+		t.Helper()
+		c := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+			},
+			DisableKeepAlives: true,
+		}}
+		res, err := c.Get("http://links/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		return string(b)
+	}
+
+	first, second := get(), get()
+	if first == "" || first == "@" {
+		t.Fatalf("unix peer keyed as %q", first)
+	}
+	if runtime.GOOS == "linux" {
+		if want := "unix:uid=" + strconv.Itoa(os.Getuid()); first != want || second != want {
+			t.Errorf("keys = %q, %q, want %q for both", first, second, want)
+		}
+	} else if first == second {
+		t.Errorf("two connections share the key %q", first)
+	}
+
+	// TCP clients keep their address.
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.7:4711"
+	if got := rateLimitKey(r); got != "192.0.2.7" {
+		t.Errorf("tcp key = %q", got)
+	}
+}
+
+func TestIngestRateLimitPerAgent(t *testing.T) {
+	s := New(0, nil, Options{
+		IngestToken: "secret",
+		Limits:      Limits{Ingest: RateLimit{Rate: 1, Burst: 1}},
+	})
+	s.addRoutes()
+	push := func(agent string) int {
+		r := httptest.NewRequest(http.MethodPost, "/api/ingest", strings.NewReader(`{"agent": "`+agent+`", "snapshots": []}`))
+		r.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		s.handler().ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	// Both come from the same address, but only the second push of the
+	// same agent is over its limit.
+	for i, step := range []struct {
+		agent string
+		want  int
+	}{
+		{"pi", http.StatusNoContent},
+		{"nas", http.StatusNoContent},
+		{"pi", http.StatusTooManyRequests},
+	} {
+		if got := push(step.agent); got != step.want {
+			t.Errorf("push %d (%s) = %d, want %d", i, step.agent, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package http
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cgroupFSRoot is where /proc and /sys/fs/cgroup are looked up; tests and
+// debugging can point it at a copied tree.
+var cgroupFSRoot = "/"
+
+// Memory limits at or above this are "unlimited" (v1 reports a page-aligned
+// LONG_MAX).
+const cgroupUnlimited = uint64(1) << 62
+
+// cgroupInfo locates the current process's cgroup directories.
+type cgroupInfo struct {
+	version   int
+	path      string
+	cpuDir    string
+	cpuacct   string
+	memoryDir string
+	container bool
+}
+
+type cgroupCPUPrev struct {
+	usageMicros  uint64
+	periods      uint64
+	throttled    uint64
+	at           time.Time
+	hasThrottled bool
+}
+
+// detectCgroup inspects <root>/proc/self/cgroup and <root>/sys/fs/cgroup.
+// It returns nil without an error when cgroups aren't available.
+func detectCgroup(root string) (*cgroupInfo, error) {
+	f, err := os.Open(filepath.Join(root, "proc/self/cgroup"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	paths := parseProcCgroup(f)
+
+	fsRoot := filepath.Join(root, "sys/fs/cgroup")
+	info := &cgroupInfo{container: inContainer(root)}
+
+	if _, err := os.Stat(filepath.Join(fsRoot, "cgroup.controllers")); err == nil {
+		info.version = 2
+		info.path = paths[""]
+		dir := cgroupDir(fsRoot, info.path)
+		info.cpuDir, info.cpuacct, info.memoryDir = dir, dir, dir
+	} else {
+		info.version = 1
+		info.path = paths["memory"]
+		if info.path == "" {
+			info.path = paths["cpu"]
+		}
+		for _, name := range []string{"cpu,cpuacct", "cpuacct,cpu", "cpu"} {
+			if fi, err := os.Stat(filepath.Join(fsRoot, name)); err == nil && fi.IsDir() {
+				info.cpuDir = cgroupDir(filepath.Join(fsRoot, name), paths["cpu"])
+				break
+			}
+		}
+		for _, name := range []string{"cpu,cpuacct", "cpuacct,cpu", "cpuacct"} {
+			if fi, err := os.Stat(filepath.Join(fsRoot, name)); err == nil && fi.IsDir() {
+				info.cpuacct = cgroupDir(filepath.Join(fsRoot, name), paths["cpuacct"])
+				break
+			}
+		}
+		if fi, err := os.Stat(filepath.Join(fsRoot, "memory")); err == nil && fi.IsDir() {
+			info.memoryDir = cgroupDir(filepath.Join(fsRoot, "memory"), paths["memory"])
+		}
+		if info.cpuDir == "" && info.memoryDir == "" {
+			return nil, nil
+		}
+	}
+
+	if !strings.HasPrefix(info.path, "/") {
+		info.path = "/" + info.path
+	}
+	for _, marker := range []string{"docker", "kubepods", "containerd", "libpod", "lxc"} {
+		if strings.Contains(info.path, marker) {
+			info.container = true
+		}
+	}
+	return info, nil
+}
+
+// parseProcCgroup maps controller name to path. The v2 unified hierarchy is
+// stored under "", and v1 lines such as "4:cpu,cpuacct:/docker/abc" are
+// split per controller.
+func parseProcCgroup(r io.Reader) map[string]string {
+	out := make(map[string]string)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(sc.Text()), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[1] == "" {
+			out[""] = parts[2]
+			continue
+		}
+		for _, ctrl := range strings.Split(parts[1], ",") {
+			out[ctrl] = parts[2]
+		}
+	}
+	return out
+}
+
+// cgroupDir joins the process's cgroup path under a hierarchy mount. Inside
+// a container with its own cgroup namespace (or a v1 bind mount) the path
+// from /proc/self/cgroup doesn't exist and the mount itself is our cgroup.
+func cgroupDir(mount, path string) string {
+	if path != "" && path != "/" {
+		dir := filepath.Join(mount, path)
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			return dir
+		}
+	}
+	return mount
+}
+
+func inContainer(root string) bool {
+	for _, marker := range []string{".dockerenv", "run/.containerenv"} {
+		if _, err := os.Stat(filepath.Join(root, marker)); err == nil {
+			return true
+		}
+	}
+	return os.Getenv("container") != ""
+}
+
+func (m *ResourceMonitor) sampleCgroup(now time.Time, hostCores int) (*CgroupStats, error) {
+	if !m.cgroupDetected {
+		info, err := detectCgroup(cgroupFSRoot)
+		m.cgroupDetected = true
+		if err != nil {
+			return nil, fmt.Errorf("detect cgroup: %w", err)
+		}
+		m.cgroup = info
+	}
+	info := m.cgroup
+	if info == nil {
+		return nil, nil
+	}
+
+	stats := &CgroupStats{
+		Version:   info.version,
+		Path:      info.path,
+		Container: info.container,
+	}
+	var errs []string
+
+	var usage cgroupCPUPrev
+	var usageErr error
+	if info.version == 2 {
+		stats.CPUQuotaCores = parseCgroupV2CPUMax(readTrimmedFile(filepath.Join(info.cpuDir, "cpu.max")))
+		usage, usageErr = readCgroupV2CPUStat(filepath.Join(info.cpuDir, "cpu.stat"))
+	} else {
+		quota, qerr := readIntFromFile(filepath.Join(info.cpuDir, "cpu.cfs_quota_us"))
+		period, perr := readIntFromFile(filepath.Join(info.cpuDir, "cpu.cfs_period_us"))
+		if qerr == nil && perr == nil && quota > 0 && period > 0 {
+			cores := float64(quota) / float64(period)
+			stats.CPUQuotaCores = &cores
+		}
+		usage, usageErr = readCgroupV1CPU(info.cpuDir, info.cpuacct)
+	}
+	if usageErr != nil {
+		errs = append(errs, fmt.Sprintf("cpu: %v", usageErr))
+	} else {
+		usage.at = now
+		prev := m.prevCgroupCPU
+		m.prevCgroupCPU = &usage
+		if prev != nil {
+			elapsed := now.Sub(prev.at).Seconds()
+			capacity := float64(hostCores)
+			if stats.CPUQuotaCores != nil {
+				capacity = *stats.CPUQuotaCores
+			}
+			if elapsed > 0 && capacity > 0 && usage.usageMicros >= prev.usageMicros {
+				used := float64(usage.usageMicros-prev.usageMicros) / 1e6 / elapsed
+				pct := clampPercent(used / capacity * 100)
+				stats.CPUPercent = &pct
+			}
+			if usage.hasThrottled && prev.hasThrottled && usage.periods > prev.periods && usage.throttled >= prev.throttled {
+				pct := clampPercent(float64(usage.throttled-prev.throttled) / float64(usage.periods-prev.periods) * 100)
+				stats.CPUThrottledPercent = &pct
+			}
+		}
+	}
+
+	if info.memoryDir != "" {
+		var used, limit uint64
+		var err error
+		var inactiveKey string
+		if info.version == 2 {
+			used, err = readUintFile(filepath.Join(info.memoryDir, "memory.current"))
+			if v := readTrimmedFile(filepath.Join(info.memoryDir, "memory.max")); v != "" && v != "max" {
+				limit, _ = strconv.ParseUint(v, 10, 64)
+			}
+			inactiveKey = "inactive_file"
+		} else {
+			used, err = readUintFile(filepath.Join(info.memoryDir, "memory.usage_in_bytes"))
+			limit, _ = readUintFile(filepath.Join(info.memoryDir, "memory.limit_in_bytes"))
+			inactiveKey = "total_inactive_file"
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("memory: %v", err))
+		} else {
+			// Match docker stats / kubectl top: page cache that can be
+			// reclaimed doesn't count as used.
+			if inactive, ok := readKeyedFile(filepath.Join(info.memoryDir, "memory.stat"))[inactiveKey]; ok && inactive < used {
+				used -= inactive
+			}
+			stats.MemoryUsedBytes = used
+			if limit > 0 && limit < cgroupUnlimited {
+				stats.MemoryLimitBytes = &limit
+				pct := clampPercent(float64(used) / float64(limit) * 100)
+				stats.MemoryUsedPercent = &pct
+			}
+		}
+	}
+
+	if info.version == 2 {
+		stats.Pressure = readPressureFiles(info.cpuDir, ".pressure")
+	}
+
+	// A process in an unlimited, non-container cgroup (e.g. a login
+	// session) would only repeat the host figures.
+	if !stats.Container && stats.CPUQuotaCores == nil && stats.MemoryLimitBytes == nil {
+		return nil, nil
+	}
+	if len(errs) > 0 {
+		return stats, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return stats, nil
+}
+
+// parseCgroupV2CPUMax parses cpu.max ("max 100000" or "150000 100000") into
+// a number of cores, nil when unlimited.
+func parseCgroupV2CPUMax(s string) *float64 {
+	fields := strings.Fields(s)
+	if len(fields) != 2 || fields[0] == "max" {
+		return nil
+	}
+	quota, err1 := strconv.ParseFloat(fields[0], 64)
+	period, err2 := strconv.ParseFloat(fields[1], 64)
+	if err1 != nil || err2 != nil || quota <= 0 || period <= 0 {
+		return nil
+	}
+	cores := quota / period
+	return &cores
+}
+
+func readCgroupV2CPUStat(path string) (cgroupCPUPrev, error) {
+	kv := readKeyedFile(path)
+	usage, ok := kv["usage_usec"]
+	if !ok {
+		return cgroupCPUPrev{}, fmt.Errorf("usage_usec missing in %s", path)
+	}
+	out := cgroupCPUPrev{usageMicros: usage}
+	if periods, ok := kv["nr_periods"]; ok {
+		out.periods = periods
+		out.throttled = kv["nr_throttled"]
+		out.hasThrottled = true
+	}
+	return out, nil
+}
+
+func readCgroupV1CPU(cpuDir, cpuacctDir string) (cgroupCPUPrev, error) {
+	if cpuacctDir == "" {
+		return cgroupCPUPrev{}, fmt.Errorf("cpuacct controller not mounted")
+	}
+	nanos, err := readUintFile(filepath.Join(cpuacctDir, "cpuacct.usage"))
+	if err != nil {
+		return cgroupCPUPrev{}, err
+	}
+	out := cgroupCPUPrev{usageMicros: nanos / 1000}
+	if cpuDir != "" {
+		kv := readKeyedFile(filepath.Join(cpuDir, "cpu.stat"))
+		if periods, ok := kv["nr_periods"]; ok {
+			out.periods = periods
+			out.throttled = kv["nr_throttled"]
+			out.hasThrottled = true
+		}
+	}
+	return out, nil
+}
+
+func readUintFile(path string) (uint64, error) {
+	s := readTrimmedFile(path)
+	if s == "" {
+		return 0, fmt.Errorf("%s unreadable or empty", path)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// readKeyedFile reads "key value" lines (cpu.stat, memory.stat).
+func readKeyedFile(path string) map[string]uint64 {
+	out := make(map[string]uint64)
+	f, err := os.Open(path)
+	if err != nil {
+		return out
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			out[fields[0]] = v
+		}
+	}
+	return out
+}
+
+func clampPercent(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 100 {
+		return 100
+	}
+	return v
+}
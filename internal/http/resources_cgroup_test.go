@@ -0,0 +1,165 @@
+package http
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSampleCgroupV2(t *testing.T) {
+	root := useCgroupFixture(t, "v2")
+	scope := filepath.Join(root, "sys/fs/cgroup/system.slice/docker-4f1c2a.scope")
+
+	m := &ResourceMonitor{}
+	now := time.Unix(1700000000, 0)
+	stats, err := m.sampleCgroup(now, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats == nil {
+		t.Fatal("no cgroup stats")
+	}
+	if stats.Version != 2 || stats.Path != "/system.slice/docker-4f1c2a.scope" || !stats.Container {
+		t.Errorf("stats = %+v", stats)
+	}
+	if !approx(stats.CPUQuotaCores, 1.5) {
+		t.Errorf("CPUQuotaCores = %v, want 1.5", stats.CPUQuotaCores)
+	}
+	if stats.CPUPercent != nil {
+		t.Errorf("CPUPercent = %v on the first sample", *stats.CPUPercent)
+	}
+	// memory.current minus inactive_file.
+	if stats.MemoryUsedBytes != 200<<20 {
+		t.Errorf("MemoryUsedBytes = %d, want %d", stats.MemoryUsedBytes, 200<<20)
+	}
+	if stats.MemoryLimitBytes == nil || *stats.MemoryLimitBytes != 512<<20 {
+		t.Errorf("MemoryLimitBytes = %v, want %d", stats.MemoryLimitBytes, 512<<20)
+	}
+	if p := stats.Pressure; p == nil || p.CPU == nil || p.CPU.Some.Avg10 != 1.5 || p.CPU.Full == nil || p.CPU.Full.TotalMicros != 23456 {
+		t.Errorf("Pressure = %+v", p)
+	} else if p.Memory == nil || p.IO != nil {
+		t.Errorf("Pressure memory = %v, io = %v; want only memory besides cpu", p.Memory, p.IO)
+	}
+
+	// 0.75s of CPU in 1s against a 1.5 core quota, 2 of 10 periods throttled.
+	stat := "usage_usec 1750000\nnr_periods 110\nnr_throttled 7\n"
+	if err := os.WriteFile(filepath.Join(scope, "cpu.stat"), []byte(stat), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = m.sampleCgroup(now.Add(time.Second), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approx(stats.CPUPercent, 50) {
+		t.Errorf("CPUPercent = %v, want 50", stats.CPUPercent)
+	}
+	if !approx(stats.CPUThrottledPercent, 20) {
+		t.Errorf("CPUThrottledPercent = %v, want 20", stats.CPUThrottledPercent)
+	}
+}
+
+func TestSampleCgroupV2Unlimited(t *testing.T) {
+	root := useCgroupFixture(t, "v2-unlimited")
+
+	m := &ResourceMonitor{}
+	now := time.Unix(1700000000, 0)
+	if _, err := m.sampleCgroup(now, 4); err != nil {
+		t.Fatal(err)
+	}
+	// 2 cores busy out of 4 host cores, since cpu.max is "max".
+	stat := "usage_usec 7000000\nnr_periods 0\nnr_throttled 0\n"
+	if err := os.WriteFile(filepath.Join(root, "sys/fs/cgroup/cpu.stat"), []byte(stat), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := m.sampleCgroup(now.Add(time.Second), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats == nil {
+		t.Fatal("no stats for a container without limits")
+	}
+	if stats.Path != "/" || !stats.Container {
+		t.Errorf("stats = %+v", stats)
+	}
+	if stats.CPUQuotaCores != nil || stats.MemoryLimitBytes != nil || stats.MemoryUsedPercent != nil {
+		t.Errorf("limits = %v, %v; want none for cpu.max and memory.max \"max\"", stats.CPUQuotaCores, stats.MemoryLimitBytes)
+	}
+	if !approx(stats.CPUPercent, 50) {
+		t.Errorf("CPUPercent = %v, want 50", stats.CPUPercent)
+	}
+	if stats.MemoryUsedBytes != 80<<20 {
+		t.Errorf("MemoryUsedBytes = %d, want %d", stats.MemoryUsedBytes, 80<<20)
+	}
+	if stats.Pressure != nil {
+		t.Errorf("Pressure = %+v without PSI files", stats.Pressure)
+	}
+}
+
+func TestSampleCgroupV1(t *testing.T) {
+	root := useCgroupFixture(t, "v1")
+	cpuDir := filepath.Join(root, "sys/fs/cgroup/cpu,cpuacct/docker/9b2e7d")
+
+	m := &ResourceMonitor{}
+	now := time.Unix(1700000000, 0)
+	stats, err := m.sampleCgroup(now, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats == nil || stats.Version != 1 || stats.Path != "/docker/9b2e7d" || !stats.Container {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if !approx(stats.CPUQuotaCores, 2) {
+		t.Errorf("CPUQuotaCores = %v, want 2", stats.CPUQuotaCores)
+	}
+	// memory.limit_in_bytes is the page-aligned LONG_MAX: unlimited.
+	if stats.MemoryLimitBytes != nil {
+		t.Errorf("MemoryLimitBytes = %d, want unlimited", *stats.MemoryLimitBytes)
+	}
+	if stats.MemoryUsedBytes != 160<<20 {
+		t.Errorf("MemoryUsedBytes = %d, want %d", stats.MemoryUsedBytes, 160<<20)
+	}
+	if stats.Pressure != nil {
+		t.Errorf("Pressure = %+v on v1", stats.Pressure)
+	}
+
+	// 1s of CPU in 2s against a 2 core quota.
+	if err := os.WriteFile(filepath.Join(cpuDir, "cpuacct.usage"), []byte("3000000000\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cpuDir, "cpu.stat"), []byte("nr_periods 70\nnr_throttled 5\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = m.sampleCgroup(now.Add(2*time.Second), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approx(stats.CPUPercent, 25) {
+		t.Errorf("CPUPercent = %v, want 25", stats.CPUPercent)
+	}
+	if !approx(stats.CPUThrottledPercent, 25) {
+		t.Errorf("CPUThrottledPercent = %v, want 25", stats.CPUThrottledPercent)
+	}
+}
+
+func TestParseCgroupV2CPUMax(t *testing.T) {
+	for in, want := range map[string]float64{
+		"150000 100000": 1.5,
+		"50000 100000":  0.5,
+		"max 100000":    -1,
+		"max":           -1,
+		"":              -1,
+		"0 100000":      -1,
+	} {
+		got := parseCgroupV2CPUMax(in)
+		if want < 0 {
+			if got != nil {
+				t.Errorf("%q = %v, want unlimited", in, *got)
+			}
+			continue
+		}
+		if !approx(got, want) {
+			t.Errorf("%q = %v, want %v", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tomek7667/links/internal/domain"
+	"github.com/tomek7667/links/pkg/api"
+)
+
+const (
+	containersSampleTTL  = 5 * time.Second
+	containerAPITimeout  = 3 * time.Second
+	containerSocketNone  = "none"
+	containerIDShortSize = 12
+)
+
+// dockerAPI is a minimal Docker Engine API client over a unix socket. Podman
+// serves the same compat endpoints on its own socket.
+type dockerAPI struct {
+	socket string
+	client *http.Client
+}
+
+type dockerContainer struct {
+	ID      string            `json:"Id"`
+	Names   []string          `json:"Names"`
+	Image   string            `json:"Image"`
+	State   string            `json:"State"`
+	Status  string            `json:"Status"`
+	Created int64             `json:"Created"`
+	Labels  map[string]string `json:"Labels"`
+	Ports   []struct {
+		IP          string `json:"IP"`
+		PrivatePort int    `json:"PrivatePort"`
+		PublicPort  int    `json:"PublicPort"`
+		Type        string `json:"Type"`
+	} `json:"Ports"`
+}
+
+type dockerInspect struct {
+	State struct {
+		StartedAt string `json:"StartedAt"`
+		Health    *struct {
+			Status string `json:"Status"`
+		} `json:"Health"`
+	} `json:"State"`
+	RestartCount int `json:"RestartCount"`
+}
+
+type dockerStats struct {
+	CPUStats struct {
+		CPUUsage struct {
+			TotalUsage uint64 `json:"total_usage"`
+		} `json:"cpu_usage"`
+		SystemCPUUsage uint64 `json:"system_cpu_usage"`
+		OnlineCPUs     int    `json:"online_cpus"`
+	} `json:"cpu_stats"`
+	MemoryStats struct {
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
+	} `json:"memory_stats"`
+}
+
+// containerCPUPrev keeps the counters of the previous stats call, since
+// one-shot stats don't include precpu_stats.
+type containerCPUPrev struct {
+	total  uint64
+	system uint64
+}
+
+// containerInspectCache holds what sampleContainers reads from the inspect
+// endpoint. It is refetched whenever the list reports a different State or
+// Status for the container: a restart keeps the ID but resets the "Up ..."
+// status, and the status text also changes every minute or hour on its own,
+// which bounds how stale the entry can get.
+type containerInspectCache struct {
+	state        string
+	status       string
+	health       string
+	startedAt    int64
+	restartCount int
+}
+
+func newDockerAPI(socket string) *dockerAPI {
+	return &dockerAPI{
+		socket: socket,
+		client: &http.Client{
+			Timeout: containerAPITimeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+func (d *dockerAPI) get(path string, query url.Values, v any) error {
+	u := "http://docker" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	res, err := d.client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("%s %s: %s", path, res.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// resolveContainerSocket picks the configured socket, DOCKER_HOST, or the
+// first default Docker/Podman socket that exists. An empty result means
+// container reporting is off.
+func resolveContainerSocket(configured string) (socket string, explicit bool) {
+	configured = strings.TrimSpace(configured)
+	if configured == containerSocketNone {
+		return "", true
+	}
+	if configured != "" {
+		return strings.TrimPrefix(configured, "unix://"), true
+	}
+	if host := os.Getenv("DOCKER_HOST"); strings.HasPrefix(host, "unix://") {
+		return strings.TrimPrefix(host, "unix://"), true
+	}
+
+	candidates := []string{"/var/run/docker.sock", "/run/podman/podman.sock"}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, "podman", "podman.sock"), filepath.Join(dir, "docker.sock"))
+	}
+	for _, c := range candidates {
+		if fi, err := os.Stat(c); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			return c, false
+		}
+	}
+	return "", false
+}
+
+// refreshContainers queries the engine in the background, like sampleSMART,
+// so a slow socket doesn't hold up the other collectors, and returns the
+// last completed result.
+func (m *ResourceMonitor) refreshContainers(now time.Time, hostIP string) ([]ContainerStats, error) {
+	if m.containerAPI == nil {
+		return nil, nil
+	}
+	m.containersMu.Lock()
+	defer m.containersMu.Unlock()
+
+	if !m.containersRunning && (m.containersUpdatedAt.IsZero() || now.Sub(m.containersUpdatedAt) >= containersSampleTTL) {
+		m.containersRunning = true
+		m.containersUpdatedAt = now
+		go func() {
+			containers, err := m.sampleContainers(hostIP)
+			m.containersMu.Lock()
+			defer m.containersMu.Unlock()
+			if containers != nil || err == nil {
+				m.containersCache = containers
+			}
+			m.containersErr = err
+			m.containersRunning = false
+		}()
+	}
+	return m.containersCache, m.containersErr
+}
+
+// sampleContainers runs on one goroutine at a time (see refreshContainers),
+// which owns prevContainerCPU and containerInspect.
+func (m *ResourceMonitor) sampleContainers(hostIP string) ([]ContainerStats, error) {
+	if m.containerAPI == nil {
+		return nil, nil
+	}
+
+	var list []dockerContainer
+	if err := m.containerAPI.get("/containers/json", url.Values{"all": {"1"}}, &list); err != nil {
+		return nil, err
+	}
+
+	out := make([]ContainerStats, 0, len(list))
+	newPrev := make(map[string]containerCPUPrev, len(list))
+	newInspect := make(map[string]containerInspectCache, len(list))
+	var errs []string
+
+	for _, c := range list {
+		cs := ContainerStats{
+			ID:     shortContainerID(c.ID),
+			Name:   containerName(c),
+			Image:  c.Image,
+			State:  c.State,
+			Status: c.Status,
+			Health: containerHealthFromStatus(c.Status),
+		}
+		if c.Created > 0 {
+			cs.CreatedAt = c.Created * 1000
+		}
+
+		for _, p := range c.Ports {
+			cs.Ports = append(cs.Ports, ContainerPort{
+				IP:          p.IP,
+				PrivatePort: p.PrivatePort,
+				PublicPort:  p.PublicPort,
+				Type:        p.Type,
+			})
+		}
+		cs.SuggestedLinks = suggestContainerLinks(cs, hostIP)
+
+		if c.State == "running" {
+			ic, ok := m.containerInspect[c.ID]
+			if !ok || ic.state != c.State || ic.status != c.Status {
+				var insp dockerInspect
+				if err := m.containerAPI.get("/containers/"+c.ID+"/json", nil, &insp); err != nil {
+					errs = append(errs, fmt.Sprintf("%s: %v", cs.Name, err))
+				} else {
+					ic = containerInspectCache{state: c.State, status: c.Status, restartCount: insp.RestartCount}
+					if t, err := time.Parse(time.RFC3339Nano, insp.State.StartedAt); err == nil {
+						ic.startedAt = t.UnixMilli()
+					}
+					if insp.State.Health != nil {
+						ic.health = insp.State.Health.Status
+					}
+				}
+			}
+			newInspect[c.ID] = ic
+			if cs.Health == "" {
+				cs.Health = ic.health
+			}
+			cs.StartedAt = ic.startedAt
+			cs.RestartCount = ic.restartCount
+
+			var st dockerStats
+			if err := m.containerAPI.get("/containers/"+c.ID+"/stats", url.Values{"stream": {"false"}, "one-shot": {"true"}}, &st); err != nil {
+				errs = append(errs, fmt.Sprintf("%s stats: %v", cs.Name, err))
+			} else {
+				cur := containerCPUPrev{total: st.CPUStats.CPUUsage.TotalUsage, system: st.CPUStats.SystemCPUUsage}
+				newPrev[c.ID] = cur
+				if prev, ok := m.prevContainerCPU[c.ID]; ok && cur.system > prev.system && cur.total >= prev.total {
+					// Same formula as `docker stats`, normalized to 0-100% of the host.
+					pct := float64(cur.total-prev.total) / float64(cur.system-prev.system) * 100
+					cs.CPUPercent = &pct
+				}
+				if st.MemoryStats.Usage > 0 {
+					used := containerMemoryUsed(st.MemoryStats.Usage, st.MemoryStats.Stats)
+					cs.MemoryBytes = &used
+					limit := st.MemoryStats.Limit
+					cs.MemoryLimitBytes = &limit
+				}
+			}
+		}
+
+		out = append(out, cs)
+	}
+
+	m.prevContainerCPU = newPrev
+	m.containerInspect = newInspect
+
+	sort.Slice(out, func(i, j int) bool {
+		if (out[i].State == "running") != (out[j].State == "running") {
+			return out[i].State == "running"
+		}
+		return out[i].Name < out[j].Name
+	})
+
+	if len(errs) > 0 {
+		return out, errors.New(strings.Join(errs, "; "))
+	}
+	return out, nil
+}
+
+// containerMemoryUsed subtracts page cache like the docker CLI does:
+// inactive_file on cgroup v2, total_inactive_file or cache on v1.
+func containerMemoryUsed(usage uint64, stats map[string]uint64) uint64 {
+	for _, key := range []string{"inactive_file", "total_inactive_file", "cache"} {
+		if v, ok := stats[key]; ok && v < usage {
+			return usage - v
+		}
+	}
+	return usage
+}
+
+func containerHealthFromStatus(status string) string {
+	s := strings.ToLower(status)
+	switch {
+	case strings.Contains(s, "(unhealthy)"):
+		return "unhealthy"
+	case strings.Contains(s, "(healthy)"):
+		return "healthy"
+	case strings.Contains(s, "(health: starting)"):
+		return "starting"
+	}
+	return ""
+}
+
+func containerName(c dockerContainer) string {
+	for _, n := range c.Names {
+		n = strings.TrimPrefix(n, "/")
+		if n != "" {
+			return n
+		}
+	}
+	return shortContainerID(c.ID)
+}
+
+func shortContainerID(id string) string {
+	if len(id) > containerIDShortSize {
+		return id[:containerIDShortSize]
+	}
+	return id
+}
+
+// suggestContainerLinks proposes a link for each published TCP port. Ports
+// bound to loopback only are skipped since other machines can't reach them.
+func suggestContainerLinks(c ContainerStats, hostIP string) []domain.Link {
+	if c.State != "running" {
+		return nil
+	}
+	published := make(map[int]struct{})
+	for _, p := range c.Ports {
+		if p.PublicPort != 0 && (p.Type == "" || p.Type == "tcp") {
+			published[p.PublicPort] = struct{}{}
+		}
+	}
+
+	var out []domain.Link
+	seen := make(map[string]struct{})
+	for _, p := range c.Ports {
+		if _, ok := published[p.PublicPort]; !ok {
+			continue
+		}
+
+		host := hostIP
+		switch ip := net.ParseIP(p.IP); {
+		case ip == nil || ip.IsUnspecified():
+		case ip.IsLoopback():
+			continue
+		default:
+			host = ip.String()
+		}
+		if host == "" {
+			continue
+		}
+
+		scheme := "http"
+		if p.PrivatePort == 443 || p.PrivatePort == 8443 {
+			scheme = "https"
+		}
+		u := scheme + "://" + net.JoinHostPort(host, strconv.Itoa(p.PublicPort))
+		// Docker lists a port once per address family, and both usually
+		// map to hostIP.
+		if _, ok := seen[api.URLKey(u)]; ok {
+			continue
+		}
+		seen[api.URLKey(u)] = struct{}{}
+		title := c.Name
+		if len(published) > 1 {
+			title = fmt.Sprintf("%s (%d)", c.Name, p.PublicPort)
+		}
+		out = append(out, domain.Link{Title: title, Url: u})
+	}
+	return out
+}
+
+// withoutSavedLinks drops suggestions whose URL is already a saved link.
+func withoutSavedLinks(containers []ContainerStats, links []domain.Link) []ContainerStats {
+	if len(containers) == 0 {
+		return containers
+	}
+	saved := make(map[string]struct{}, len(links))
+	for _, l := range links {
+		saved[api.URLKey(l.Url)] = struct{}{}
+	}
+	out := make([]ContainerStats, len(containers))
+	for i, c := range containers {
+		out[i] = c
+		if len(c.SuggestedLinks) == 0 {
+			continue
+		}
+		out[i].SuggestedLinks = nil
+		for _, l := range c.SuggestedLinks {
+			if _, ok := saved[api.URLKey(l.Url)]; !ok {
+				out[i].SuggestedLinks = append(out[i].SuggestedLinks, l)
+			}
+		}
+	}
+	return out
+}
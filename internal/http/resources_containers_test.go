@@ -0,0 +1,210 @@
+package http
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tomek7667/links/internal/domain"
+)
+
+// serveDockerSocket serves h on a unix socket and returns its path.
+func serveDockerSocket(t *testing.T, h http.Handler) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(h)
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return socket
+}
+
+// fakeDockerSocket serves the Engine API endpoints sampleContainers uses on
+// a unix socket. Each stats call adds 10ms of container CPU and 100ms of
+// system CPU, so the second sample reports 10%.
+func fakeDockerSocket(t *testing.T) string {
+	t.Helper()
+	var statsCalls atomic.Int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /containers/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("all") != "1" {
+			t.Errorf("list without all=1: %s", r.URL)
+		}
+		_, _ = w.Write([]byte(`[
+			{"Id": "0123456789abcdef0123", "Names": ["/web"], "Image": "nginx", "State": "running",
+			 "Status": "Up 2 hours (healthy)", "Created": 1700000000,
+			 "Ports": [{"IP": "0.0.0.0", "PrivatePort": 80, "PublicPort": 8080, "Type": "tcp"},
+			           {"IP": "::", "PrivatePort": 80, "PublicPort": 8080, "Type": "tcp"},
+			           {"IP": "127.0.0.1", "PrivatePort": 9000, "PublicPort": 9000, "Type": "tcp"}]},
+			{"Id": "fedcba9876543210fedc", "Names": ["/batch"], "Image": "busybox", "State": "exited",
+			 "Status": "Exited (0) 3 days ago", "Created": 1690000000}
+		]`))
+	})
+	mux.HandleFunc("GET /containers/0123456789abcdef0123/json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"State": {"StartedAt": "2024-01-02T03:04:05.5Z"}, "RestartCount": 2}`))
+	})
+	mux.HandleFunc("GET /containers/0123456789abcdef0123/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("stream") != "false" {
+			t.Errorf("stats without stream=false: %s", r.URL)
+		}
+		n := uint64(statsCalls.Add(1))
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"cpu_stats": map[string]any{
+				"cpu_usage":        map[string]any{"total_usage": n * 10_000_000},
+				"system_cpu_usage": n * 100_000_000,
+				"online_cpus":      4,
+			},
+			"memory_stats": map[string]any{
+				"usage": 300 << 20,
+				"limit": 1 << 30,
+				"stats": map[string]any{"inactive_file": 100 << 20},
+			},
+		})
+	})
+
+	return serveDockerSocket(t, mux)
+}
+
+func TestSampleContainers(t *testing.T) {
+	m := &ResourceMonitor{containerAPI: newDockerAPI(fakeDockerSocket(t))}
+
+	first, err := m.sampleContainers("192.168.1.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 2 {
+		t.Fatalf("got %d containers, want 2", len(first))
+	}
+	if first[0].CPUPercent != nil {
+		t.Errorf("first sample has CPU %v, want none without a previous reading", *first[0].CPUPercent)
+	}
+
+	got, err := m.sampleContainers("192.168.1.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	web, batch := got[0], got[1]
+	if web.Name != "web" || batch.Name != "batch" {
+		t.Fatalf("order = %s, %s; want running first", web.Name, batch.Name)
+	}
+	if web.ID != "0123456789ab" || web.Health != "healthy" || web.RestartCount != 2 {
+		t.Errorf("web = %+v", web)
+	}
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 5e8, time.UTC).UnixMilli(); web.StartedAt != want {
+		t.Errorf("StartedAt = %d, want %d", web.StartedAt, want)
+	}
+	if web.CPUPercent == nil || *web.CPUPercent < 9.99 || *web.CPUPercent > 10.01 {
+		t.Errorf("CPUPercent = %v, want 10", web.CPUPercent)
+	}
+	if web.MemoryBytes == nil || *web.MemoryBytes != 200<<20 {
+		t.Errorf("MemoryBytes = %v, want %d (usage minus inactive_file)", web.MemoryBytes, 200<<20)
+	}
+	if len(web.SuggestedLinks) != 1 || web.SuggestedLinks[0].Url != "http://192.168.1.10:8080" {
+		t.Errorf("SuggestedLinks = %+v, want only the non-loopback port", web.SuggestedLinks)
+	}
+	if batch.CPUPercent != nil || batch.SuggestedLinks != nil {
+		t.Errorf("stopped container has stats or links: %+v", batch)
+	}
+}
+
+func TestSampleContainersReinspectsOnStatusChange(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		status   = "Up 2 hours"
+		started  = "2024-01-02T03:04:05Z"
+		inspects int
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /containers/json", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = json.NewEncoder(w).Encode([]map[string]any{
+			{"Id": "abc", "Names": []string{"/web"}, "State": "running", "Status": status},
+		})
+	})
+	mux.HandleFunc("GET /containers/abc/json", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		inspects++
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"State":        map[string]any{"StartedAt": started, "Health": map[string]any{"Status": "starting"}},
+			"RestartCount": inspects - 1,
+		})
+	})
+	mux.HandleFunc("GET /containers/abc/stats", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	})
+	m := &ResourceMonitor{containerAPI: newDockerAPI(serveDockerSocket(t, mux))}
+
+	sample := func() ContainerStats {
+		t.Helper()
+		got, err := m.sampleContainers("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got[0]
+	}
+
+	sample()
+	if web := sample(); inspects != 1 || web.RestartCount != 0 || web.Health != "starting" {
+		t.Errorf("unchanged status: %d inspects, container %+v; want the cached inspect", inspects, web)
+	}
+
+	// A restart between two samples keeps State "running" and the ID.
+	mu.Lock()
+	status, started = "Up 1 second", "2024-01-02T05:00:00Z"
+	mu.Unlock()
+	web := sample()
+	if inspects != 2 || web.RestartCount != 1 {
+		t.Errorf("after a restart: %d inspects, RestartCount %d; want 2, 1", inspects, web.RestartCount)
+	}
+	if want := time.Date(2024, 1, 2, 5, 0, 0, 0, time.UTC).UnixMilli(); web.StartedAt != want {
+		t.Errorf("StartedAt = %d, want %d", web.StartedAt, want)
+	}
+}
+
+func TestSampleContainersUnreachable(t *testing.T) {
+	m := &ResourceMonitor{containerAPI: newDockerAPI(filepath.Join(t.TempDir(), "missing.sock"))}
+	if _, err := m.sampleContainers(""); err == nil || !strings.Contains(err.Error(), "missing.sock") {
+		t.Errorf("err = %v, want a dial error naming the socket", err)
+	}
+}
+
+func TestWithoutSavedLinks(t *testing.T) {
+	containers := []ContainerStats{
+		{Name: "web", SuggestedLinks: []domain.Link{
+			{Title: "web (80)", Url: "http://10.0.0.5:80"},
+			{Title: "web (8443)", Url: "https://10.0.0.5:8443"},
+			{Title: "web (9000)", Url: "http://10.0.0.5:9000"},
+		}},
+		{Name: "db"},
+	}
+	saved := []domain.Link{
+		// Differs from the suggestions only by default port, case and a
+		// trailing slash.
+		{Title: "Web", Url: "http://10.0.0.5/"},
+		{Title: "Web admin", Url: "HTTPS://10.0.0.5:8443/"},
+	}
+
+	got := withoutSavedLinks(containers, saved)
+	if len(got[0].SuggestedLinks) != 1 || got[0].SuggestedLinks[0].Url != "http://10.0.0.5:9000" {
+		t.Errorf("web suggestions = %+v, want only port 9000", got[0].SuggestedLinks)
+	}
+	if got[1].SuggestedLinks != nil {
+		t.Errorf("db suggestions = %+v", got[1].SuggestedLinks)
+	}
+	if len(containers[0].SuggestedLinks) != 3 {
+		t.Error("the input containers were modified")
+	}
+}
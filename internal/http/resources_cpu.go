@@ -13,44 +13,68 @@ import (
 	"github.com/shirou/gopsutil/v3/host"
 )
 
-func (m *ResourceMonitor) sampleCPUPercent() (float64, error) {
+func (m *ResourceMonitor) sampleCPUPercent() (float64, *CPUBreakdown, error) {
 	times, err := cpu.Times(false)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 	if len(times) == 0 {
-		return 0, nil
+		return 0, nil, nil
 	}
 
 	t := times[0]
-	total := cpuTimesTotal(t)
-	idle := t.Idle + t.Iowait
-
 	if !m.havePrevCPU {
-		m.prevTotal = total
-		m.prevIdle = idle
+		m.prevCPUTimes = t
 		m.havePrevCPU = true
-		return 0, nil
+		return 0, nil, nil
 	}
 
-	totalDelta := total - m.prevTotal
-	idleDelta := idle - m.prevIdle
+	prev := m.prevCPUTimes
+	m.prevCPUTimes = t
 
-	m.prevTotal = total
-	m.prevIdle = idle
+	usage, ok := cpuBusyPercent(prev, t)
+	if !ok {
+		return 0, nil, nil
+	}
+	return usage, cpuTimesBreakdown(prev, t), nil
+}
 
+// cpuBusyPercent is the non-idle share between two samples; iowait counts
+// as idle.
+func cpuBusyPercent(prev, cur cpu.TimesStat) (float64, bool) {
+	totalDelta := cpuTimesTotal(cur) - cpuTimesTotal(prev)
 	if totalDelta <= 0 {
-		return 0, nil
+		return 0, false
 	}
+	idleDelta := (cur.Idle + cur.Iowait) - (prev.Idle + prev.Iowait)
 
 	usage := (totalDelta - idleDelta) / totalDelta * 100
 	if usage < 0 {
-		return 0, nil
+		return 0, true
 	}
 	if usage > 100 {
-		return 100, nil
+		return 100, true
+	}
+	return usage, true
+}
+
+func cpuTimesBreakdown(prev, cur cpu.TimesStat) *CPUBreakdown {
+	totalDelta := cpuTimesTotal(cur) - cpuTimesTotal(prev)
+	if totalDelta <= 0 {
+		return nil
+	}
+	share := func(a, b float64) float64 {
+		return min(max((b-a)/totalDelta*100, 0), 100)
+	}
+	return &CPUBreakdown{
+		User:   share(prev.User, cur.User),
+		Nice:   share(prev.Nice, cur.Nice),
+		System: share(prev.System, cur.System),
+		IRQ:    share(prev.Irq+prev.Softirq, cur.Irq+cur.Softirq),
+		IOWait: share(prev.Iowait, cur.Iowait),
+		Steal:  share(prev.Steal, cur.Steal),
+		Idle:   share(prev.Idle, cur.Idle),
 	}
-	return usage, nil
 }
 
 type cpuFreqSummary struct {
@@ -197,8 +221,10 @@ func isFiniteFloat(v float64) bool {
 	return !math.IsNaN(v) && !math.IsInf(v, 0)
 }
 
+// cpuTimesTotal leaves out Guest and GuestNice: Linux already counts them
+// in User and Nice.
 func cpuTimesTotal(t cpu.TimesStat) float64 {
-	return t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal + t.Guest + t.GuestNice
+	return t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
 }
 
 func cpuTimesTotalPtr(t *cpu.TimesStat) float64 {
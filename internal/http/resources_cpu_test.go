@@ -0,0 +1,105 @@
+package http
+
+import (
+	"math"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+func TestCPUTimesBreakdown(t *testing.T) {
+	prev := cpu.TimesStat{
+		User: 1000, Nice: 10, System: 400, Idle: 8000, Iowait: 100,
+		Irq: 20, Softirq: 30, Steal: 5, Guest: 200, GuestNice: 0,
+	}
+	tests := []struct {
+		name string
+		cur  cpu.TimesStat
+		want *CPUBreakdown
+		busy float64
+	}{
+		{
+			// 200s elapsed in total.
+			name: "mixed",
+			cur: cpu.TimesStat{
+				User: 1060, Nice: 10, System: 430, Idle: 8070, Iowait: 120,
+				Irq: 25, Softirq: 35, Steal: 15, Guest: 200,
+			},
+			want: &CPUBreakdown{User: 30, System: 15, IRQ: 5, IOWait: 10, Steal: 5, Idle: 35},
+			busy: 55,
+		},
+		{
+			// A VM guest's time is part of User already and isn't
+			// counted twice.
+			name: "guest",
+			cur: cpu.TimesStat{
+				User: 1080, Nice: 10, System: 400, Idle: 8020, Iowait: 100,
+				Irq: 20, Softirq: 30, Steal: 5, Guest: 260,
+			},
+			want: &CPUBreakdown{User: 80, Idle: 20},
+			busy: 80,
+		},
+		{
+			name: "idle",
+			cur: cpu.TimesStat{
+				User: 1000, Nice: 10, System: 400, Idle: 8100, Iowait: 100,
+				Irq: 20, Softirq: 30, Steal: 5, Guest: 200,
+			},
+			want: &CPUBreakdown{Idle: 100},
+			busy: 0,
+		},
+		{
+			// Two readings within one tick.
+			name: "zero delta",
+			cur:  prev,
+		},
+		{
+			// A counter going backwards (e.g. idle on some kernels after
+			// a CPU goes offline) is clamped rather than negative.
+			name: "counter went backwards",
+			cur: cpu.TimesStat{
+				User: 1100, Nice: 10, System: 400, Idle: 7990, Iowait: 100,
+				Irq: 20, Softirq: 30, Steal: 5, Guest: 200,
+			},
+			want: &CPUBreakdown{User: 100},
+			busy: 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cpuTimesBreakdown(prev, tt.cur)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("breakdown = %+v, want none", *got)
+				}
+				if _, ok := cpuBusyPercent(prev, tt.cur); ok {
+					t.Error("cpuBusyPercent reported a value")
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("no breakdown")
+			}
+			for _, f := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"User", got.User, tt.want.User},
+				{"Nice", got.Nice, tt.want.Nice},
+				{"System", got.System, tt.want.System},
+				{"IRQ", got.IRQ, tt.want.IRQ},
+				{"IOWait", got.IOWait, tt.want.IOWait},
+				{"Steal", got.Steal, tt.want.Steal},
+				{"Idle", got.Idle, tt.want.Idle},
+			} {
+				if math.Abs(f.got-f.want) > 1e-9 {
+					t.Errorf("%s = %v, want %v", f.name, f.got, f.want)
+				}
+			}
+			busy, ok := cpuBusyPercent(prev, tt.cur)
+			if !ok || math.Abs(busy-tt.busy) > 1e-9 {
+				t.Errorf("cpuBusyPercent = %v, %v; want %v", busy, ok, tt.busy)
+			}
+		})
+	}
+}
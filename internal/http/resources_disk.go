@@ -3,6 +3,7 @@ package http
 import (
 	"fmt"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -30,6 +31,7 @@ func (m *ResourceMonitor) getDiskMeta() (map[string]diskMeta, error) {
 				continue
 			}
 			meta[p.MountPoint] = diskMeta{
+				Disk:              d.Name,
 				DriveType:         driveType,
 				StorageController: strings.TrimSpace(d.StorageController.String()),
 				Model:             model,
@@ -125,6 +127,12 @@ func (m *ResourceMonitor) sampleDisks() ([]DiskStats, error) {
 		selected["/"] = struct{}{}
 	}
 
+	var roots map[string]mountRoot
+	if runtime.GOOS == "linux" {
+		roots = readMountRoots(mountInfoPaths)
+	}
+	applyDiskRules(m.opts.Disks, selected, partsByMount, roots)
+
 	if len(selected) == 0 {
 		for _, p := range parts {
 			if p.Mountpoint != "" {
@@ -158,23 +166,42 @@ func (m *ResourceMonitor) sampleDisks() ([]DiskStats, error) {
 
 		ds := DiskStats{
 			Mountpoint:  mp,
+			Name:        m.opts.Disks.Names[mp],
 			Device:      device,
 			Filesystem:  fstype,
 			TotalBytes:  usage.Total,
 			UsedBytes:   usage.Used,
 			UsedPercent: usage.UsedPercent,
 		}
+		var dm diskMeta
 		if meta != nil {
 			if m, ok := meta[mp]; ok {
+				dm = m
 				ds.DriveType = m.DriveType
 				ds.Model = m.Model
 			}
 		}
+		ds.PhysicalDevice = physicalDiskName(device, dm)
 		out = append(out, ds)
 	}
 
+	var errs []string
 	if metaErr != nil {
-		return out, fmt.Errorf("disk metadata: %w", metaErr)
+		errs = append(errs, fmt.Sprintf("disk metadata: %v", metaErr))
+	}
+	if slices.ContainsFunc(out, func(d DiskStats) bool { return d.Filesystem == "zfs" }) {
+		pools, datasets, err := m.sampleZFS(time.Now())
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		for i := range out {
+			if out[i].Filesystem == "zfs" {
+				attachZFS(&out[i], pools, datasets)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return out, fmt.Errorf("%s", strings.Join(errs, "; "))
 	}
 	return out, nil
 }
@@ -0,0 +1,145 @@
+package http
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// /proc/diskstats prints the time fields as unsigned int and the rest as
+// unsigned long, which is also 32 bits on 32-bit kernels such as Raspberry Pi
+// OS. gopsutil turns sectors into bytes, so those wrap 512 times later.
+const (
+	diskCounterWrap = 1 << 32
+	diskBytesWrap   = diskCounterWrap * 512
+)
+
+// sampleDiskIO returns per-device I/O rates keyed by the device name used in
+// disk.IOCounters (e.g. "sda", "nvme0n1", "C:"), derived from counter deltas
+// between successive calls.
+func (m *ResourceMonitor) sampleDiskIO(now time.Time) (map[string]DiskIOStats, error) {
+	counters, err := disk.IOCounters()
+	if err != nil {
+		return nil, err
+	}
+
+	out := diskIORates(m.prevDiskIO, counters, now.Sub(m.prevDiskIOAt).Seconds())
+	m.prevDiskIO = counters
+	m.prevDiskIOAt = now
+	return out, nil
+}
+
+// diskIORates computes the rates between two disk.IOCounters readings taken
+// elapsedSec apart. Devices without a previous reading only get their name.
+func diskIORates(prev, cur map[string]disk.IOCountersStat, elapsedSec float64) map[string]DiskIOStats {
+	out := make(map[string]DiskIOStats, len(cur))
+	for name, c := range cur {
+		io := DiskIOStats{Device: name}
+		if p, ok := prev[name]; ok && elapsedSec > 0 {
+			io.ReadBytesPerSec = diskCounterRate(p.ReadBytes, c.ReadBytes, diskBytesWrap, elapsedSec)
+			io.WriteBytesPerSec = diskCounterRate(p.WriteBytes, c.WriteBytes, diskBytesWrap, elapsedSec)
+			io.ReadIOPS = diskCounterRate(p.ReadCount, c.ReadCount, diskCounterWrap, elapsedSec)
+			io.WriteIOPS = diskCounterRate(p.WriteCount, c.WriteCount, diskCounterWrap, elapsedSec)
+
+			// IoTime and WeightedIO are milliseconds spent doing I/O.
+			elapsedMs := elapsedSec * 1000
+			io.UtilPercent = min(diskCounterRate(p.IoTime, c.IoTime, diskCounterWrap, elapsedMs)*100, 100)
+			io.QueueDepth = diskCounterRate(p.WeightedIO, c.WeightedIO, diskCounterWrap, elapsedMs)
+
+			reads, ok1 := diskCounterDelta(p.ReadCount, c.ReadCount, diskCounterWrap)
+			writes, ok2 := diskCounterDelta(p.WriteCount, c.WriteCount, diskCounterWrap)
+			readMs, ok3 := diskCounterDelta(p.ReadTime, c.ReadTime, diskCounterWrap)
+			writeMs, ok4 := diskCounterDelta(p.WriteTime, c.WriteTime, diskCounterWrap)
+			if ok1 && ok2 && ok3 && ok4 && reads+writes > 0 {
+				io.AwaitMs = float64(readMs+writeMs) / float64(reads+writes)
+			}
+		}
+		out[name] = io
+	}
+	return out
+}
+
+func diskCounterRate(prev, cur, wrap uint64, elapsed float64) float64 {
+	d, ok := diskCounterDelta(prev, cur, wrap)
+	if !ok {
+		return 0
+	}
+	return float64(d) / elapsed
+}
+
+// diskCounterDelta returns how far a counter moved. A counter that went
+// backwards from the top half of its range to the bottom half has
+// wrapped; otherwise it was reset (the device was re-added) and there is no
+// delta.
+func diskCounterDelta(prev, cur, wrap uint64) (uint64, bool) {
+	if cur >= prev {
+		return cur - prev, true
+	}
+	if prev < wrap && prev >= wrap/2 && cur < wrap/2 {
+		return wrap - prev + cur, true
+	}
+	return 0, false
+}
+
+// physicalDiskName resolves the whole-disk device backing a partition so
+// that mounts on sda1 and sda2 report sda's I/O. It falls back to the
+// partition's own name when the parent can't be determined.
+func physicalDiskName(device string, meta diskMeta) string {
+	if meta.Disk != "" {
+		return meta.Disk
+	}
+	device = strings.TrimSpace(device)
+	if device == "" {
+		return ""
+	}
+	if runtime.GOOS == "windows" {
+		return device
+	}
+	if !strings.HasPrefix(device, "/dev/") {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(device); err == nil {
+		device = resolved
+	}
+	name := filepath.Base(device)
+	if runtime.GOOS != "linux" {
+		return name
+	}
+	return blockDeviceDisk(sysfsRoot, name)
+}
+
+// blockDeviceDisk returns the disk a partition such as sda1, nvme0n1p1 or
+// mmcblk0p2 belongs to: its class/block entry links into the disk's own
+// directory. Whole disks and device-mapper devices are returned as is.
+func blockDeviceDisk(root, name string) string {
+	sysPath := filepath.Join(root, "class", "block", name)
+	if _, err := os.Stat(filepath.Join(sysPath, "partition")); err != nil {
+		return name
+	}
+	resolved, err := filepath.EvalSymlinks(sysPath)
+	if err != nil {
+		return name
+	}
+	return filepath.Base(filepath.Dir(resolved))
+}
+
+func attachDiskIO(disks []DiskStats, io map[string]DiskIOStats) []DiskStats {
+	if len(disks) == 0 {
+		return disks
+	}
+	out := make([]DiskStats, len(disks))
+	for i, d := range disks {
+		out[i] = d
+		if d.PhysicalDevice == "" {
+			continue
+		}
+		if s, ok := io[d.PhysicalDevice]; ok {
+			out[i].IO = &s
+		}
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+func TestDiskIORates(t *testing.T) {
+	prev := map[string]disk.IOCountersStat{
+		"sda": {
+			ReadCount: 1000, WriteCount: 500, ReadBytes: 10 << 20, WriteBytes: 4 << 20,
+			ReadTime: 2000, WriteTime: 1000, IoTime: 10_000, WeightedIO: 20_000,
+		},
+		// Close to the top of 32-bit counters, as on a Pi after a while.
+		"mmcblk0": {
+			ReadCount: diskCounterWrap - 100, WriteCount: 10, ReadBytes: diskBytesWrap - 1<<20,
+			ReadTime: diskCounterWrap - 50, IoTime: diskCounterWrap - 1000, WeightedIO: 100,
+		},
+		"sdb": {ReadCount: 5_000_000, ReadBytes: 80 << 30, IoTime: 900_000},
+	}
+	cur := map[string]disk.IOCountersStat{
+		"sda": {
+			ReadCount: 1300, WriteCount: 600, ReadBytes: 30 << 20, WriteBytes: 5 << 20,
+			ReadTime: 2300, WriteTime: 1100, IoTime: 11_000, WeightedIO: 22_000,
+		},
+		"mmcblk0": {
+			ReadCount: 100, WriteCount: 10, ReadBytes: 3 << 20,
+			ReadTime: 150, IoTime: 500, WeightedIO: 100,
+		},
+		// Re-added: the counters start over. That isn't a wrap.
+		"sdb": {ReadCount: 20, ReadBytes: 1 << 20, IoTime: 30},
+		"sdc": {ReadCount: 1},
+	}
+
+	got := diskIORates(prev, cur, 2)
+	want := map[string]DiskIOStats{
+		"sda": {
+			Device: "sda", ReadBytesPerSec: 10 << 20, WriteBytesPerSec: 512 << 10,
+			ReadIOPS: 150, WriteIOPS: 50, UtilPercent: 50, QueueDepth: 1,
+			AwaitMs: 1, // 400ms busy over 400 requests
+		},
+		"mmcblk0": {
+			Device: "mmcblk0", ReadBytesPerSec: 2 << 20, ReadIOPS: 100,
+			UtilPercent: 75, AwaitMs: 1,
+		},
+		"sdb": {Device: "sdb"},
+		"sdc": {Device: "sdc"},
+	}
+	for name, w := range want {
+		if got[name] != w {
+			t.Errorf("%s = %+v\nwant %+v", name, got[name], w)
+		}
+	}
+
+	// The first sample has nothing to compare with.
+	for name, io := range diskIORates(nil, cur, 0) {
+		if io != (DiskIOStats{Device: name}) {
+			t.Errorf("first sample %s = %+v", name, io)
+		}
+	}
+}
+
+func TestBlockDeviceDisk(t *testing.T) {
+	root := t.TempDir()
+	devices := map[string]string{
+		"sda1":      "devices/pci0000:00/0000:00:17.0/ata1/host0/target0:0:0/0:0:0:0/block/sda/sda1",
+		"sda":       "devices/pci0000:00/0000:00:17.0/ata1/host0/target0:0:0/0:0:0:0/block/sda",
+		"nvme0n1p1": "devices/pci0000:00/0000:00:1d.0/0000:3d:00.0/nvme/nvme0/nvme0n1/nvme0n1p1",
+		"mmcblk0p2": "devices/platform/emmc2bus/fe340000.mmc/mmc_host/mmc0/mmc0:aaaa/block/mmcblk0/mmcblk0p2",
+		"dm-0":      "devices/virtual/block/dm-0",
+	}
+	for name, dir := range devices {
+		files := map[string]string{"size": "1000"}
+		if name != "sda" && name != "dm-0" {
+			files["partition"] = "1"
+		}
+		writeSysfsFiles(t, filepath.Join(root, dir), files)
+		symlink(t, filepath.Join("..", "..", dir), filepath.Join(root, "class", "block", name))
+	}
+
+	for name, want := range map[string]string{
+		"sda1":      "sda",
+		"sda":       "sda",
+		"nvme0n1p1": "nvme0n1",
+		"mmcblk0p2": "mmcblk0",
+		"dm-0":      "dm-0",
+		"loop9":     "loop9",
+	} {
+		if got := blockDeviceDisk(root, name); got != want {
+			t.Errorf("blockDeviceDisk(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	if got := physicalDiskName("/dev/sda1", diskMeta{Disk: "sdb"}); got != "sdb" {
+		t.Errorf("physicalDiskName with a known disk = %q", got)
+	}
+	if got := physicalDiskName("tmpfs", diskMeta{}); got != "" {
+		t.Errorf("physicalDiskName(tmpfs) = %q, want none", got)
+	}
+}
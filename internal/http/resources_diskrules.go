@@ -0,0 +1,197 @@
+package http
+
+import (
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// DiskRules adjust which mounts the disks table shows. Mount and device
+// patterns are path.Match globs; a trailing "/**" also matches everything
+// below the prefix. Exclude rules win over include rules, and include rules
+// win over the built-in selection.
+type DiskRules struct {
+	IncludeMounts  []string
+	ExcludeMounts  []string
+	IncludeDevices []string
+	ExcludeDevices []string
+	IncludeFSTypes []string
+	ExcludeFSTypes []string
+	// Names maps a mountpoint to the label shown instead of it.
+	Names map[string]string
+}
+
+func (r DiskRules) included(p disk.PartitionStat) bool {
+	return matchAnyGlob(r.IncludeMounts, p.Mountpoint) ||
+		matchAnyGlob(r.IncludeDevices, p.Device) ||
+		slices.Contains(r.IncludeFSTypes, p.Fstype)
+}
+
+func (r DiskRules) excluded(p disk.PartitionStat) bool {
+	return matchAnyGlob(r.ExcludeMounts, p.Mountpoint) ||
+		matchAnyGlob(r.ExcludeDevices, p.Device) ||
+		slices.Contains(r.ExcludeFSTypes, p.Fstype)
+}
+
+func matchAnyGlob(patterns []string, s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "/**"); ok {
+			if s == prefix || strings.HasPrefix(s, prefix+"/") || prefix == "" {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyDiskRules adds mounts matched by include rules, drops excluded ones,
+// and collapses block-device mounts that show the same directory tree, which
+// weren't asked for explicitly, keeping the shortest mountpoint: the same
+// filesystem mounted twice, and bind mounts of a directory of a filesystem
+// that is also mounted normally. Btrfs subvolumes are separate trees and all
+// stay. Mounts missing from roots count as mounting the filesystem root.
+func applyDiskRules(rules DiskRules, selected map[string]struct{}, parts map[string]disk.PartitionStat, roots map[string]mountRoot) {
+	for mp, p := range parts {
+		if rules.included(p) {
+			selected[mp] = struct{}{}
+		}
+	}
+
+	type mount struct {
+		mp     string
+		device string
+		root   mountRoot
+	}
+	var mounts []mount
+	for mp := range selected {
+		p, ok := parts[mp]
+		if !ok {
+			p = disk.PartitionStat{Mountpoint: mp}
+		}
+		if rules.excluded(p) {
+			delete(selected, mp)
+			continue
+		}
+		if !strings.HasPrefix(p.Device, "/dev/") || matchAnyGlob(rules.IncludeMounts, mp) {
+			continue
+		}
+		root, ok := roots[mp]
+		if !ok {
+			root = mountRoot{Root: "/"}
+		}
+		mounts = append(mounts, mount{mp: mp, device: p.Device, root: root})
+	}
+
+	mounted := make(map[string]bool)
+	for _, m := range mounts {
+		if !m.root.Bind {
+			mounted[m.device] = true
+		}
+	}
+	byTree := make(map[string]string)
+	for _, m := range mounts {
+		key := m.device + "\x00" + m.root.Root
+		if m.root.Bind {
+			if mounted[m.device] {
+				delete(selected, m.mp)
+				continue
+			}
+			key = m.device + "\x00bind"
+		}
+		prev, ok := byTree[key]
+		if !ok {
+			byTree[key] = m.mp
+			continue
+		}
+		keep, drop := prev, m.mp
+		if len(m.mp) < len(prev) || (len(m.mp) == len(prev) && m.mp < prev) {
+			keep, drop = m.mp, prev
+		}
+		byTree[key] = keep
+		delete(selected, drop)
+	}
+}
+
+// mountRoot is the directory of a filesystem mounted at a mountpoint, the
+// fourth field of mountinfo: "/" for a normal mount, the subvolume for btrfs
+// and the source directory for bind mounts.
+type mountRoot struct {
+	Root string
+	// Bind is set for bind mounts of a directory below the filesystem (or
+	// subvolume) root.
+	Bind bool
+}
+
+// mountInfoPaths are tried in order, like gopsutil does for the partition
+// list, so both see the same mounts.
+var mountInfoPaths = []string{"/proc/1/mountinfo", "/proc/self/mountinfo"}
+
+func readMountRoots(paths []string) map[string]mountRoot {
+	for _, p := range paths {
+		if b, err := os.ReadFile(p); err == nil {
+			return parseMountInfo(string(b))
+		}
+	}
+	return nil
+}
+
+// parseMountInfo maps mountpoints to their roots. A line looks like
+//
+//	36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+//
+// and btrfs lists the mounted subvolume as subvol= in the last field.
+func parseMountInfo(text string) map[string]mountRoot {
+	out := make(map[string]mountRoot)
+	for _, line := range strings.Split(text, "\n") {
+		pre, post, ok := strings.Cut(line, " - ")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(pre)
+		if len(fields) < 5 {
+			continue
+		}
+		root := unescapeMountInfo(fields[3])
+		mr := mountRoot{Root: root, Bind: root != "/"}
+		if sb := strings.Fields(post); len(sb) >= 3 && sb[0] == "btrfs" {
+			for _, opt := range strings.Split(sb[2], ",") {
+				if subvol, ok := strings.CutPrefix(opt, "subvol="); ok && subvol == root {
+					mr.Bind = false
+				}
+			}
+		}
+		out[unescapeMountInfo(fields[4])] = mr
+	}
+	return out
+}
+
+// unescapeMountInfo undoes the octal escapes ("\040" for a space) the kernel
+// writes for whitespace and backslashes in paths.
+func unescapeMountInfo(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// mountInfoPartitions builds the partition list gopsutil would return for a
+// mountinfo fixture, along with the parsed mount roots.
+func mountInfoPartitions(t *testing.T, fixture string) (map[string]disk.PartitionStat, map[string]mountRoot) {
+	t.Helper()
+	text := string(readFixture(t, "mountinfo", fixture))
+	parts := make(map[string]disk.PartitionStat)
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		pre, post, _ := strings.Cut(line, " - ")
+		mp := unescapeMountInfo(strings.Fields(pre)[4])
+		sb := strings.Fields(post)
+		parts[mp] = disk.PartitionStat{Mountpoint: mp, Fstype: sb[0], Device: sb[1]}
+	}
+	return parts, parseMountInfo(text)
+}
+
+func blockDeviceMounts(parts map[string]disk.PartitionStat) map[string]struct{} {
+	selected := make(map[string]struct{})
+	for mp, p := range parts {
+		if strings.HasPrefix(p.Device, "/dev/") {
+			selected[mp] = struct{}{}
+		}
+	}
+	return selected
+}
+
+func TestParseMountInfo(t *testing.T) {
+	_, roots := mountInfoPartitions(t, "btrfs")
+	for mp, want := range map[string]mountRoot{
+		"/":               {Root: "/@"},
+		"/home":           {Root: "/@home"},
+		"/boot/efi":       {Root: "/"},
+		"/srv/data":       {Root: "/@/srv/data", Bind: true},
+		"/srv/my files":   {Root: "/my files", Bind: true},
+		"/srv/exports":    {Root: "/exports", Bind: true},
+		"/run/user/1000":  {Root: "/"},
+		"/media/backup":   {Root: "/"},
+		"/nonexistent/mp": {},
+	} {
+		if got := roots[mp]; got != want {
+			t.Errorf("%s = %+v, want %+v", mp, got, want)
+		}
+	}
+}
+
+func TestApplyDiskRulesCollapse(t *testing.T) {
+	parts, roots := mountInfoPartitions(t, "btrfs")
+
+	tests := []struct {
+		name  string
+		rules DiskRules
+		roots map[string]mountRoot
+		want  []string
+	}{
+		{
+			// Subvolumes stay, bind mounts of a directory and the second
+			// mount of /dev/sdb1 go. /dev/sdc1 is only bind mounted, so
+			// one of those stays.
+			name:  "default",
+			roots: roots,
+			want:  []string{"/", "/boot/efi", "/export", "/home", "/mnt/backup", "/var"},
+		},
+		{
+			name:  "included bind mount",
+			rules: DiskRules{IncludeMounts: []string{"/srv/data"}},
+			roots: roots,
+			want:  []string{"/", "/boot/efi", "/export", "/home", "/mnt/backup", "/srv/data", "/var"},
+		},
+		{
+			name:  "excluded mount frees its duplicate",
+			rules: DiskRules{ExcludeMounts: []string{"/mnt/**"}},
+			roots: roots,
+			want:  []string{"/", "/boot/efi", "/export", "/home", "/media/backup", "/var"},
+		},
+		{
+			// Without mountinfo every mount of a device looks the same.
+			name: "no mountinfo",
+			want: []string{"/", "/boot/efi", "/export", "/mnt/backup"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected := blockDeviceMounts(parts)
+			applyDiskRules(tt.rules, selected, parts, tt.roots)
+			got := slices.Sorted(maps.Keys(selected))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("mounts = %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnescapeMountInfo(t *testing.T) {
+	for in, want := range map[string]string{
+		`/srv/my\040files`: "/srv/my files",
+		`/a\011b\134c`:     "/a\tb\\c",
+		`/plain`:           "/plain",
+		`/trailing\04`:     `/trailing\04`,
+	} {
+		if got := unescapeMountInfo(in); got != want {
+			t.Errorf("unescapeMountInfo(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
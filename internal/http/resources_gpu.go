@@ -14,14 +14,44 @@ import (
 	"github.com/jaypipes/ghw"
 )
 
+// nvidiaSMITimeout bounds each nvidia-smi run; a wedged driver can make it
+// hang.
+const nvidiaSMITimeout = 2 * time.Second
+
 type nvidiaSMIGPU struct {
-	Name          string
-	UtilPercent   float64
-	MemUsedBytes  uint64
-	MemTotalBytes uint64
-	TempC         float64
+	Name           string
+	BusID          string
+	UUID           string
+	UtilPercent    float64
+	MemUsedBytes   uint64
+	MemTotalBytes  uint64
+	TempC          float64
+	PowerWatts     *float64
+	PowerLimit     *float64
+	SMClockMHz     *float64
+	MemClockMHz    *float64
+	FanPercent     *float64
+	EncoderPercent *float64
+	DecoderPercent *float64
+	ECCCorrected   *uint64
+	ECCUncorrected *uint64
+	Processes      []GPUProcess
 }
 
+// nvidiaSMIBasicFields work on every driver; the extended set adds fields
+// older drivers reject, in which case we fall back to the basic query.
+var (
+	nvidiaSMIBasicFields = []string{"name", "utilization.gpu", "memory.used", "memory.total", "temperature.gpu"}
+	nvidiaSMIFields      = append(append([]string(nil), nvidiaSMIBasicFields...),
+		"pci.bus_id", "uuid",
+		"power.draw", "power.limit",
+		"clocks.sm", "clocks.mem",
+		"fan.speed",
+		"utilization.encoder", "utilization.decoder",
+		"ecc.errors.corrected.volatile.total", "ecc.errors.uncorrected.volatile.total",
+	)
+)
+
 func (m *ResourceMonitor) getGPUMeta() ([]GPUStats, error) {
 	if m.gpuMeta != nil && time.Since(m.gpuMetaUpdatedAt) < hardwareMetaTTL {
 		return m.gpuMeta, nil
@@ -34,7 +64,7 @@ func (m *ResourceMonitor) getGPUMeta() ([]GPUStats, error) {
 
 	gpus := make([]GPUStats, 0, len(info.GraphicsCards))
 	for _, card := range info.GraphicsCards {
-		gs := GPUStats{Index: card.Index}
+		gs := GPUStats{Index: card.Index, PCIAddress: pciAddressFromGHW(card.Address)}
 		if card.DeviceInfo != nil {
 			gs.Driver = strings.TrimSpace(card.DeviceInfo.Driver)
 			if card.DeviceInfo.Vendor != nil {
@@ -57,17 +87,29 @@ func (m *ResourceMonitor) getGPUMeta() ([]GPUStats, error) {
 	return gpus, nil
 }
 
-func (m *ResourceMonitor) sampleGPUs() ([]GPUStats, error) {
+func (m *ResourceMonitor) sampleGPUs(now time.Time) ([]GPUStats, error) {
 	base, ghwErr := m.getGPUMeta()
 	gpus := append([]GPUStats(nil), base...)
 
-	metrics, smiErr := nvidiaSMIMetrics()
+	metrics, smiErr := m.refreshNvidiaSMI(now)
 	if len(metrics) > 0 {
 		gpus = mergeNvidiaSMIMetrics(gpus, metrics)
 	}
 
+	var drmErr error
+	if runtime.GOOS == "linux" {
+		var drm []drmGPU
+		drm, drmErr = m.sampleDRMGPUs(now)
+		if len(drm) > 0 {
+			gpus = mergeDRMMetrics(gpus, drm)
+		}
+	}
+
 	if len(gpus) == 0 {
 		if ghwErr != nil && smiErr != nil {
+			if drmErr != nil {
+				return nil, fmt.Errorf("gpu: ghw=%v; nvidia-smi=%v; sysfs=%v", ghwErr, smiErr, drmErr)
+			}
 			return nil, fmt.Errorf("gpu: ghw=%v; nvidia-smi=%v", ghwErr, smiErr)
 		}
 		return nil, nil
@@ -75,59 +117,193 @@ func (m *ResourceMonitor) sampleGPUs() ([]GPUStats, error) {
 	return gpus, nil
 }
 
+// refreshNvidiaSMI runs nvidia-smi in the background, like sampleSMART,
+// since it can take up to nvidiaSMITimeout per query, and returns the last
+// completed result.
+func (m *ResourceMonitor) refreshNvidiaSMI(now time.Time) ([]nvidiaSMIGPU, error) {
+	m.nvidiaMu.Lock()
+	defer m.nvidiaMu.Unlock()
+
+	if !m.nvidiaRunning && (m.nvidiaUpdatedAt.IsZero() || now.Sub(m.nvidiaUpdatedAt) >= gpusSampleTTL) {
+		m.nvidiaRunning = true
+		m.nvidiaUpdatedAt = now
+		go func() {
+			metrics, err := nvidiaSMIMetrics()
+			m.nvidiaMu.Lock()
+			defer m.nvidiaMu.Unlock()
+			if metrics != nil || err == nil {
+				m.nvidiaCache = metrics
+			}
+			m.nvidiaErr = err
+			m.nvidiaRunning = false
+		}()
+	}
+	return m.nvidiaCache, m.nvidiaErr
+}
+
 func nvidiaSMIMetrics() ([]nvidiaSMIGPU, error) {
 	path, err := findNvidiaSMI()
 	if err != nil {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	fields := nvidiaSMIFields
+	out, err := runNvidiaSMI(path, "--query-gpu="+strings.Join(fields, ","), "--format=csv,noheader,nounits")
+	if err != nil {
+		fields = nvidiaSMIBasicFields
+		out, err = runNvidiaSMI(path, "--query-gpu="+strings.Join(fields, ","), "--format=csv,noheader,nounits")
+		if err != nil {
+			return nil, err
+		}
+	}
+	metrics := parseNvidiaSMICSV(out, fields)
+
+	if len(metrics) > 0 && metrics[0].UUID != "" {
+		apps, err := runNvidiaSMI(path, "--query-compute-apps=gpu_uuid,pid,process_name,used_memory", "--format=csv,noheader,nounits")
+		if err == nil {
+			byUUID := parseNvidiaSMIApps(apps)
+			for i := range metrics {
+				metrics[i].Processes = byUUID[metrics[i].UUID]
+			}
+		}
+	}
+	return metrics, nil
+}
+
+func runNvidiaSMI(path string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), nvidiaSMITimeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, path,
-		"--query-gpu=name,utilization.gpu,memory.used,memory.total,temperature.gpu",
-		"--format=csv,noheader,nounits",
-	)
-	out, err := cmd.CombinedOutput()
+	out, err := exec.CommandContext(ctx, path, args...).CombinedOutput()
 	if err != nil {
 		if ctx.Err() != nil {
-			return nil, ctx.Err()
+			return "", ctx.Err()
 		}
-		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+		return "", fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
 	}
+	return string(out), nil
+}
 
-	text := strings.TrimSpace(string(out))
+// parseNvidiaSMICSV parses `--format=csv,noheader,nounits` output whose
+// columns are fields, in order. Unsupported values ("[N/A]",
+// "[Not Supported]") are left nil.
+func parseNvidiaSMICSV(text string, fields []string) []nvidiaSMIGPU {
+	text = strings.TrimSpace(text)
 	if text == "" {
-		return nil, nil
+		return nil
 	}
 
-	lines := strings.Split(text, "\n")
-	metrics := make([]nvidiaSMIGPU, 0, len(lines))
-	for _, line := range lines {
+	var metrics []nvidiaSMIGPU
+	for _, line := range strings.Split(text, "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
 		parts := strings.Split(line, ",")
-		if len(parts) < 5 {
+		if len(parts) < len(fields) {
 			continue
 		}
 
-		name := strings.TrimSpace(parts[0])
-		util, _ := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
-		memUsedMiB, _ := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
-		memTotalMiB, _ := strconv.ParseFloat(strings.TrimSpace(parts[3]), 64)
-		temp, _ := strconv.ParseFloat(strings.TrimSpace(parts[4]), 64)
+		var g nvidiaSMIGPU
+		for i, field := range fields {
+			raw := strings.TrimSpace(parts[i])
+			num := func() *float64 {
+				v, err := strconv.ParseFloat(raw, 64)
+				if err != nil || !isFiniteFloat(v) {
+					return nil
+				}
+				return &v
+			}
+			count := func() *uint64 {
+				v, err := strconv.ParseUint(raw, 10, 64)
+				if err != nil {
+					return nil
+				}
+				return &v
+			}
+			switch field {
+			case "name":
+				g.Name = raw
+			case "pci.bus_id":
+				g.BusID = raw
+			case "uuid":
+				g.UUID = raw
+			case "utilization.gpu":
+				g.UtilPercent, _ = strconv.ParseFloat(raw, 64)
+			case "memory.used":
+				mib, _ := strconv.ParseFloat(raw, 64)
+				g.MemUsedBytes = uint64(mib * 1024 * 1024)
+			case "memory.total":
+				mib, _ := strconv.ParseFloat(raw, 64)
+				g.MemTotalBytes = uint64(mib * 1024 * 1024)
+			case "temperature.gpu":
+				g.TempC, _ = strconv.ParseFloat(raw, 64)
+			case "power.draw":
+				g.PowerWatts = num()
+			case "power.limit":
+				g.PowerLimit = num()
+			case "clocks.sm":
+				g.SMClockMHz = num()
+			case "clocks.mem":
+				g.MemClockMHz = num()
+			case "fan.speed":
+				g.FanPercent = num()
+			case "utilization.encoder":
+				g.EncoderPercent = num()
+			case "utilization.decoder":
+				g.DecoderPercent = num()
+			case "ecc.errors.corrected.volatile.total":
+				g.ECCCorrected = count()
+			case "ecc.errors.uncorrected.volatile.total":
+				g.ECCUncorrected = count()
+			}
+		}
+		metrics = append(metrics, g)
+	}
+	return metrics
+}
 
-		metrics = append(metrics, nvidiaSMIGPU{
-			Name:          name,
-			UtilPercent:   util,
-			MemUsedBytes:  uint64(memUsedMiB * 1024 * 1024),
-			MemTotalBytes: uint64(memTotalMiB * 1024 * 1024),
-			TempC:         temp,
-		})
+// parseNvidiaSMIApps parses `--query-compute-apps=gpu_uuid,pid,process_name,used_memory`
+// into processes per GPU UUID. Process names may contain commas.
+func parseNvidiaSMIApps(text string) map[string][]GPUProcess {
+	out := make(map[string][]GPUProcess)
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		parts := strings.Split(strings.TrimSpace(line), ",")
+		if len(parts) < 4 {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			continue
+		}
+		proc := GPUProcess{
+			PID:  pid,
+			Name: strings.TrimSpace(strings.Join(parts[2:len(parts)-1], ",")),
+		}
+		if mib, err := strconv.ParseFloat(strings.TrimSpace(parts[len(parts)-1]), 64); err == nil {
+			proc.MemoryUsedBytes = uint64(mib * 1024 * 1024)
+		}
+		uuid := strings.TrimSpace(parts[0])
+		out[uuid] = append(out[uuid], proc)
 	}
-	return metrics, nil
+	for uuid := range out {
+		procs := out[uuid]
+		sort.Slice(procs, func(i, j int) bool { return procs[i].MemoryUsedBytes > procs[j].MemoryUsedBytes })
+	}
+	return out
+}
+
+// nvidiaBusIDToPCI turns "00000000:01:00.0" into sysfs form "0000:01:00.0".
+func nvidiaBusIDToPCI(busID string) string {
+	busID = strings.ToLower(strings.TrimSpace(busID))
+	domain, rest, ok := strings.Cut(busID, ":")
+	if !ok {
+		return busID
+	}
+	if len(domain) > 4 {
+		domain = domain[len(domain)-4:]
+	}
+	return domain + ":" + rest
 }
 
 func mergeNvidiaSMIMetrics(gpus []GPUStats, metrics []nvidiaSMIGPU) []GPUStats {
@@ -140,46 +316,71 @@ func mergeNvidiaSMIMetrics(gpus []GPUStats, metrics []nvidiaSMIGPU) []GPUStats {
 
 	if len(nvidiaIdx) == 0 {
 		for i, m := range metrics {
-			util := m.UtilPercent
-			temp := m.TempC
-			memUsed := m.MemUsedBytes
-			memTotal := m.MemTotalBytes
-			gpus = append(gpus, GPUStats{
-				Index:              i,
-				Name:               m.Name,
-				Vendor:             "NVIDIA",
-				UtilizationPercent: &util,
-				MemoryUsedBytes:    &memUsed,
-				MemoryTotalBytes:   &memTotal,
-				TemperatureC:       &temp,
-			})
+			gs := GPUStats{
+				Index:      i,
+				Name:       m.Name,
+				Vendor:     "NVIDIA",
+				PCIAddress: nvidiaBusIDToPCI(m.BusID),
+			}
+			applyNvidiaSMIMetrics(&gs, m)
+			gpus = append(gpus, gs)
 		}
 		return gpus
 	}
 
+	used := make(map[int]bool, len(nvidiaIdx))
 	for i, m := range metrics {
-		if i >= len(nvidiaIdx) {
-			break
+		// Prefer the PCI address; fall back to the order nvidia-smi lists
+		// cards in.
+		pos := -1
+		if m.BusID != "" {
+			addr := nvidiaBusIDToPCI(m.BusID)
+			for _, idx := range nvidiaIdx {
+				if !used[idx] && strings.EqualFold(gpus[idx].PCIAddress, addr) {
+					pos = idx
+					break
+				}
+			}
 		}
-		pos := nvidiaIdx[i]
-
-		util := m.UtilPercent
-		temp := m.TempC
-		memUsed := m.MemUsedBytes
-		memTotal := m.MemTotalBytes
+		if pos == -1 {
+			if i >= len(nvidiaIdx) || used[nvidiaIdx[i]] {
+				continue
+			}
+			pos = nvidiaIdx[i]
+		}
+		used[pos] = true
 
 		if strings.TrimSpace(gpus[pos].Name) == "" {
 			gpus[pos].Name = m.Name
 		}
-		gpus[pos].UtilizationPercent = &util
-		gpus[pos].TemperatureC = &temp
-		gpus[pos].MemoryUsedBytes = &memUsed
-		gpus[pos].MemoryTotalBytes = &memTotal
+		applyNvidiaSMIMetrics(&gpus[pos], m)
 	}
 
 	return gpus
 }
 
+func applyNvidiaSMIMetrics(gs *GPUStats, m nvidiaSMIGPU) {
+	util := m.UtilPercent
+	temp := m.TempC
+	memUsed := m.MemUsedBytes
+	memTotal := m.MemTotalBytes
+
+	gs.UtilizationPercent = &util
+	gs.TemperatureC = &temp
+	gs.MemoryUsedBytes = &memUsed
+	gs.MemoryTotalBytes = &memTotal
+	gs.PowerWatts = m.PowerWatts
+	gs.PowerLimitWatts = m.PowerLimit
+	gs.ClockMHz = m.SMClockMHz
+	gs.MemoryClockMHz = m.MemClockMHz
+	gs.FanPercent = m.FanPercent
+	gs.EncoderPercent = m.EncoderPercent
+	gs.DecoderPercent = m.DecoderPercent
+	gs.ECCCorrected = m.ECCCorrected
+	gs.ECCUncorrected = m.ECCUncorrected
+	gs.Processes = m.Processes
+}
+
 func findNvidiaSMI() (string, error) {
 	if p, err := exec.LookPath("nvidia-smi"); err == nil {
 		return p, nil
@@ -0,0 +1,250 @@
+package http
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var drmCardName = regexp.MustCompile(`^card[0-9]+$`)
+
+// drmGPU holds what the amdgpu, i915 and xe drivers expose in sysfs.
+type drmGPU struct {
+	Card        string
+	PCIAddress  string
+	Driver      string
+	UtilPercent *float64
+	MemUsed     *uint64
+	MemTotal    *uint64
+	TempC       *float64
+	PowerWatts  *float64
+	ClockMHz    *float64
+	MaxClockMHz *float64
+	// IdleMs is the cumulative RC6 / gtidle residency used to derive
+	// utilisation on Intel GPUs.
+	IdleMs    uint64
+	HasIdleMs bool
+}
+
+type gpuIdleSample struct {
+	idleMs uint64
+	at     time.Time
+}
+
+// readDRMGPUs walks <root>/class/drm/card* for amdgpu, i915 and xe devices.
+func readDRMGPUs(root string) ([]drmGPU, error) {
+	base := filepath.Join(root, "class", "drm")
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var out []drmGPU
+	for _, e := range entries {
+		if !drmCardName.MatchString(e.Name()) {
+			continue
+		}
+		cardDir := filepath.Join(base, e.Name())
+		devDir := filepath.Join(cardDir, "device")
+		uevent := readUevent(filepath.Join(devDir, "uevent"))
+
+		g := drmGPU{
+			Card:       e.Name(),
+			PCIAddress: uevent["PCI_SLOT_NAME"],
+			Driver:     uevent["DRIVER"],
+		}
+		switch g.Driver {
+		case "amdgpu":
+			readAMDGPU(&g, devDir)
+		case "i915":
+			readI915(&g, cardDir)
+		case "xe":
+			readXe(&g, devDir)
+		default:
+			continue
+		}
+		readDRMHwmon(&g, devDir)
+		out = append(out, g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Card < out[j].Card })
+	return out, nil
+}
+
+func readAMDGPU(g *drmGPU, devDir string) {
+	if v, err := readIntFromFile(filepath.Join(devDir, "gpu_busy_percent")); err == nil {
+		util := float64(v)
+		g.UtilPercent = &util
+	}
+	if v, err := readUintFile(filepath.Join(devDir, "mem_info_vram_used")); err == nil {
+		g.MemUsed = &v
+	}
+	if v, err := readUintFile(filepath.Join(devDir, "mem_info_vram_total")); err == nil && v > 0 {
+		g.MemTotal = &v
+	}
+}
+
+func readI915(g *drmGPU, cardDir string) {
+	// Multi-GT kernels (6.2+) move the files under gt/gt0.
+	gtDir := filepath.Join(cardDir, "gt", "gt0")
+	if _, err := os.Stat(gtDir); err == nil {
+		setMHz(&g.ClockMHz, filepath.Join(gtDir, "rps_act_freq_mhz"))
+		setMHz(&g.MaxClockMHz, filepath.Join(gtDir, "rps_max_freq_mhz"))
+		g.IdleMs, g.HasIdleMs = readIdleMs(filepath.Join(gtDir, "rc6_residency_ms"))
+		return
+	}
+	setMHz(&g.ClockMHz, filepath.Join(cardDir, "gt_act_freq_mhz"))
+	setMHz(&g.MaxClockMHz, filepath.Join(cardDir, "gt_max_freq_mhz"))
+	g.IdleMs, g.HasIdleMs = readIdleMs(filepath.Join(cardDir, "power", "rc6_residency_ms"))
+}
+
+func readXe(g *drmGPU, devDir string) {
+	gtDir := filepath.Join(devDir, "tile0", "gt0")
+	setMHz(&g.ClockMHz, filepath.Join(gtDir, "freq0", "act_freq"))
+	setMHz(&g.MaxClockMHz, filepath.Join(gtDir, "freq0", "max_freq"))
+	g.IdleMs, g.HasIdleMs = readIdleMs(filepath.Join(gtDir, "gtidle", "idle_residency_ms"))
+}
+
+// readDRMHwmon fills temperature, power and (for amdgpu) the shader clock
+// from the device's hwmon directory.
+func readDRMHwmon(g *drmGPU, devDir string) {
+	dirs, _ := filepath.Glob(filepath.Join(devDir, "hwmon", "hwmon*"))
+	for _, dir := range dirs {
+		if g.TempC == nil {
+			if v, err := readIntFromFile(filepath.Join(dir, "temp1_input")); err == nil {
+				temp := float64(v) / 1000
+				g.TempC = &temp
+			}
+		}
+		if g.PowerWatts == nil {
+			for _, name := range []string{"power1_average", "power1_input"} {
+				if v, err := readIntFromFile(filepath.Join(dir, name)); err == nil {
+					w := float64(v) / 1e6
+					g.PowerWatts = &w
+					break
+				}
+			}
+		}
+		if g.ClockMHz == nil {
+			if v, err := readIntFromFile(filepath.Join(dir, "freq1_input")); err == nil {
+				mhz := float64(v) / 1e6
+				g.ClockMHz = &mhz
+			}
+		}
+	}
+}
+
+func setMHz(dst **float64, path string) {
+	if v, err := readIntFromFile(path); err == nil && v > 0 {
+		mhz := float64(v)
+		*dst = &mhz
+	}
+}
+
+func readIdleMs(path string) (uint64, bool) {
+	v, err := readUintFile(path)
+	return v, err == nil
+}
+
+func readUevent(path string) map[string]string {
+	out := make(map[string]string)
+	f, err := os.Open(path)
+	if err != nil {
+		return out
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if k, v, ok := strings.Cut(strings.TrimSpace(sc.Text()), "="); ok {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// sampleDRMGPUs reads sysfs and turns idle residency counters into a busy
+// percent using the previous sample.
+func (m *ResourceMonitor) sampleDRMGPUs(now time.Time) ([]drmGPU, error) {
+	gpus, err := readDRMGPUs(sysfsRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	prev := m.prevGPUIdle
+	m.prevGPUIdle = make(map[string]gpuIdleSample, len(gpus))
+	for i := range gpus {
+		g := &gpus[i]
+		if !g.HasIdleMs {
+			continue
+		}
+		m.prevGPUIdle[g.Card] = gpuIdleSample{idleMs: g.IdleMs, at: now}
+		p, ok := prev[g.Card]
+		if !ok || g.UtilPercent != nil || g.IdleMs < p.idleMs {
+			continue
+		}
+		elapsedMs := float64(now.Sub(p.at).Milliseconds())
+		if elapsedMs <= 0 {
+			continue
+		}
+		busy := clampPercent(100 - float64(g.IdleMs-p.idleMs)/elapsedMs*100)
+		g.UtilPercent = &busy
+	}
+	return gpus, nil
+}
+
+// mergeDRMMetrics matches sysfs readings to ghw cards by PCI address and
+// appends any card ghw didn't report.
+func mergeDRMMetrics(gpus []GPUStats, metrics []drmGPU) []GPUStats {
+	for _, d := range metrics {
+		pos := -1
+		for i := range gpus {
+			if d.PCIAddress != "" && strings.EqualFold(gpus[i].PCIAddress, d.PCIAddress) {
+				pos = i
+				break
+			}
+		}
+		if pos == -1 {
+			vendor := "Intel"
+			if d.Driver == "amdgpu" {
+				vendor = "AMD"
+			}
+			gpus = append(gpus, GPUStats{
+				Index:      len(gpus),
+				Name:       vendor + " GPU (" + d.Card + ")",
+				Vendor:     vendor,
+				Driver:     d.Driver,
+				PCIAddress: d.PCIAddress,
+			})
+			pos = len(gpus) - 1
+		}
+
+		g := &gpus[pos]
+		if g.Driver == "" {
+			g.Driver = d.Driver
+		}
+		g.UtilizationPercent = d.UtilPercent
+		g.MemoryUsedBytes = d.MemUsed
+		g.MemoryTotalBytes = d.MemTotal
+		g.TemperatureC = d.TempC
+		g.PowerWatts = d.PowerWatts
+		g.ClockMHz = d.ClockMHz
+		g.MaxClockMHz = d.MaxClockMHz
+	}
+	return gpus
+}
+
+// pciAddressFromGHW normalises ghw's address ("0000:03:00.0") to the
+// PCI_SLOT_NAME form used in sysfs.
+func pciAddressFromGHW(addr string) string {
+	addr = strings.ToLower(strings.TrimSpace(addr))
+	if addr != "" && strings.Count(addr, ":") == 1 {
+		addr = "0000:" + addr
+	}
+	return addr
+}
@@ -0,0 +1,112 @@
+package http
+
+import "testing"
+
+func TestReadDRMGPUs(t *testing.T) {
+	gpus, err := readDRMGPUs(fakeDRMSysfs(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gpus) != 4 {
+		t.Fatalf("got %d GPUs, want 4: %+v", len(gpus), gpus)
+	}
+	amd, i915, i915gt, xe := gpus[0], gpus[1], gpus[2], gpus[3]
+
+	t.Run("amdgpu", func(t *testing.T) {
+		if amd.Card != "card0" || amd.Driver != "amdgpu" || amd.PCIAddress != "0000:03:00.0" {
+			t.Errorf("gpu = %+v", amd)
+		}
+		want := map[string]any{
+			"util":     37.0,
+			"memUsed":  uint64(1 << 30),
+			"memTotal": uint64(8 << 30),
+			"temp":     54.0,
+			"power":    42.0,
+			"clock":    1850.0,
+			"maxClock": nil,
+		}
+		checkDRMGPU(t, amd, want)
+		if amd.HasIdleMs {
+			t.Error("amdgpu has an idle counter")
+		}
+	})
+	t.Run("i915", func(t *testing.T) {
+		checkDRMGPU(t, i915, map[string]any{
+			"util":     nil,
+			"memUsed":  nil,
+			"memTotal": nil,
+			"temp":     nil,
+			"power":    3.5,
+			"clock":    350.0,
+			"maxClock": 1300.0,
+		})
+		if !i915.HasIdleMs || i915.IdleMs != 120000 {
+			t.Errorf("idle = %d (%v), want 120000", i915.IdleMs, i915.HasIdleMs)
+		}
+	})
+	t.Run("i915 gt0", func(t *testing.T) {
+		// A parked GPU reports 0 MHz, which is left unset.
+		checkDRMGPU(t, i915gt, map[string]any{"clock": nil, "maxClock": 2400.0})
+		if !i915gt.HasIdleMs || i915gt.IdleMs != 987654 {
+			t.Errorf("idle = %d (%v), want 987654", i915gt.IdleMs, i915gt.HasIdleMs)
+		}
+	})
+	t.Run("xe", func(t *testing.T) {
+		if xe.Driver != "xe" || xe.PCIAddress != "0000:07:00.0" {
+			t.Errorf("gpu = %+v", xe)
+		}
+		checkDRMGPU(t, xe, map[string]any{
+			"util":     nil,
+			"temp":     61.5,
+			"power":    nil,
+			"clock":    1550.0,
+			"maxClock": 2050.0,
+		})
+		if !xe.HasIdleMs || xe.IdleMs != 4242 {
+			t.Errorf("idle = %d (%v), want 4242 from gt0", xe.IdleMs, xe.HasIdleMs)
+		}
+	})
+}
+
+func checkDRMGPU(t *testing.T, g drmGPU, want map[string]any) {
+	t.Helper()
+	got := map[string]any{
+		"util":     ptrValue(g.UtilPercent),
+		"memUsed":  ptrValue(g.MemUsed),
+		"memTotal": ptrValue(g.MemTotal),
+		"temp":     ptrValue(g.TempC),
+		"power":    ptrValue(g.PowerWatts),
+		"clock":    ptrValue(g.ClockMHz),
+		"maxClock": ptrValue(g.MaxClockMHz),
+	}
+	for k, w := range want {
+		if got[k] != w {
+			t.Errorf("%s = %v (%T), want %v (%T)", k, got[k], got[k], w, w)
+		}
+	}
+}
+
+func TestReadDRMGPUsMissingTree(t *testing.T) {
+	gpus, err := readDRMGPUs(t.TempDir())
+	if err != nil || gpus != nil {
+		t.Errorf("got %v, %v; want nothing for a tree without drm", gpus, err)
+	}
+}
+
+func TestMergeDRMMetrics(t *testing.T) {
+	gpus, err := readDRMGPUs(fakeDRMSysfs(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ghw := []GPUStats{{Index: 0, Name: "Navi 23", Vendor: "AMD", PCIAddress: pciAddressFromGHW("03:00.0")}}
+	merged := mergeDRMMetrics(ghw, gpus)
+	if len(merged) != 4 {
+		t.Fatalf("got %d cards, want 4", len(merged))
+	}
+	if merged[0].Name != "Navi 23" || merged[0].Driver != "amdgpu" || ptrValue(merged[0].UtilizationPercent) != 37.0 {
+		t.Errorf("matched card = %+v", merged[0])
+	}
+	if merged[3].Name != "Intel GPU (card3)" || merged[3].Index != 3 || merged[3].Driver != "xe" {
+		t.Errorf("appended card = %+v", merged[3])
+	}
+}
@@ -0,0 +1,222 @@
+package http
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestParseNvidiaSMICSV(t *testing.T) {
+	tests := []struct {
+		fixture string
+		fields  []string
+		want    []map[string]any
+	}{
+		{"query-gpu.csv", nvidiaSMIFields, []map[string]any{
+			{
+				"name":           "NVIDIA GeForce RTX 3090",
+				"busID":          "00000000:01:00.0",
+				"uuid":           "GPU-5d3a1c7e-9b2f-4e61-8a0d-1f2e3c4b5a69",
+				"util":           27.0,
+				"memUsed":        uint64(1843 << 20),
+				"memTotal":       uint64(24576 << 20),
+				"temp":           54.0,
+				"power":          112.34,
+				"powerLimit":     350.0,
+				"smClock":        1395.0,
+				"memClock":       9751.0,
+				"fan":            30.0,
+				"encoder":        0.0,
+				"decoder":        3.0,
+				"eccCorrected":   nil,
+				"eccUncorrected": nil,
+			},
+			{
+				// Passively cooled datacenter card: no fan, ECC enabled.
+				"name":           "Tesla T4",
+				"busID":          "00000000:3B:00.0",
+				"util":           0.0,
+				"memUsed":        uint64(0),
+				"fan":            nil,
+				"power":          9.87,
+				"eccCorrected":   uint64(2),
+				"eccUncorrected": uint64(0),
+			},
+			{
+				// Laptop GPU that reports almost nothing beyond the basics.
+				"name":           "NVIDIA GeForce GTX 1050 Ti with Max-Q Design",
+				"util":           5.0,
+				"memTotal":       uint64(4096 << 20),
+				"power":          nil,
+				"powerLimit":     nil,
+				"smClock":        139.0,
+				"fan":            nil,
+				"encoder":        nil,
+				"decoder":        nil,
+				"eccCorrected":   nil,
+				"eccUncorrected": nil,
+			},
+		}},
+		{"query-gpu-basic.csv", nvidiaSMIBasicFields, []map[string]any{
+			{
+				"name":     "GeForce GTX 780",
+				"busID":    "",
+				"uuid":     "",
+				"util":     12.0,
+				"memUsed":  uint64(1024 << 20),
+				"memTotal": uint64(3072 << 20),
+				"temp":     41.0,
+				"power":    nil,
+				"fan":      nil,
+			},
+			{
+				"name":    "GeForce GT 710",
+				"util":    0.0,
+				"memUsed": uint64(210 << 20),
+			},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			metrics := parseNvidiaSMICSV(string(readFixture(t, "nvidia-smi", tt.fixture)), tt.fields)
+			if len(metrics) != len(tt.want) {
+				t.Fatalf("got %d GPUs, want %d", len(metrics), len(tt.want))
+			}
+			for i, m := range metrics {
+				got := map[string]any{
+					"name":           m.Name,
+					"busID":          m.BusID,
+					"uuid":           m.UUID,
+					"util":           m.UtilPercent,
+					"memUsed":        m.MemUsedBytes,
+					"memTotal":       m.MemTotalBytes,
+					"temp":           m.TempC,
+					"power":          ptrValue(m.PowerWatts),
+					"powerLimit":     ptrValue(m.PowerLimit),
+					"smClock":        ptrValue(m.SMClockMHz),
+					"memClock":       ptrValue(m.MemClockMHz),
+					"fan":            ptrValue(m.FanPercent),
+					"encoder":        ptrValue(m.EncoderPercent),
+					"decoder":        ptrValue(m.DecoderPercent),
+					"eccCorrected":   ptrValue(m.ECCCorrected),
+					"eccUncorrected": ptrValue(m.ECCUncorrected),
+				}
+				for k, want := range tt.want[i] {
+					if got[k] != want {
+						t.Errorf("gpu %d: %s = %v (%T), want %v (%T)", i, k, got[k], got[k], want, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseNvidiaSMICSVShortLines(t *testing.T) {
+	// Lines with fewer columns than requested (a driver error message, a
+	// truncated row) are skipped.
+	text := "NVIDIA-SMI has failed because it couldn't communicate with the NVIDIA driver.\n" +
+		"Tesla T4, 0, 0\n"
+	if got := parseNvidiaSMICSV(text, nvidiaSMIBasicFields); got != nil {
+		t.Errorf("got %+v, want nothing", got)
+	}
+	if got := parseNvidiaSMICSV("\n", nvidiaSMIFields); got != nil {
+		t.Errorf("got %+v for empty output", got)
+	}
+}
+
+func TestParseNvidiaSMIApps(t *testing.T) {
+	apps := parseNvidiaSMIApps(string(readFixture(t, "nvidia-smi", "compute-apps.csv")))
+	if len(apps) != 2 {
+		t.Fatalf("got %d GPUs, want 2: %+v", len(apps), apps)
+	}
+
+	rtx := apps["GPU-5d3a1c7e-9b2f-4e61-8a0d-1f2e3c4b5a69"]
+	want := []GPUProcess{
+		// Sorted by memory, and the comma in the command line is kept.
+		{PID: 3054, Name: "/opt/render/bin/worker --tiles=4,4", MemoryUsedBytes: 10240 << 20},
+		{PID: 2211, Name: "/usr/bin/python3", MemoryUsedBytes: 812 << 20},
+	}
+	if len(rtx) != len(want) {
+		t.Fatalf("rtx processes = %+v, want %+v", rtx, want)
+	}
+	for i := range want {
+		if rtx[i] != want[i] {
+			t.Errorf("rtx process %d = %+v, want %+v", i, rtx[i], want[i])
+		}
+	}
+
+	// used_memory is [N/A] without permission to see other users' usage.
+	t4 := apps["GPU-a81f0d24-6c3e-4b7a-9e52-0c9d8b7a6f51"]
+	if len(t4) != 1 || t4[0] != (GPUProcess{PID: 4120, Name: "ollama"}) {
+		t.Errorf("t4 processes = %+v", t4)
+	}
+
+	if got := parseNvidiaSMIApps(""); len(got) != 0 {
+		t.Errorf("got %+v for no running apps", got)
+	}
+}
+
+func TestNvidiaBusIDToPCI(t *testing.T) {
+	for in, want := range map[string]string{
+		"00000000:01:00.0": "0000:01:00.0",
+		"00000000:3B:00.0": "0000:3b:00.0",
+		"0000:02:00.0":     "0000:02:00.0",
+		"garbage":          "garbage",
+	} {
+		if got := nvidiaBusIDToPCI(in); got != want {
+			t.Errorf("nvidiaBusIDToPCI(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRefreshNvidiaSMIInBackground(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as nvidia-smi")
+	}
+	// A fake nvidia-smi on an old driver without the extended fields, which
+	// blocks until the test lets it answer.
+	dir := t.TempDir()
+	release := filepath.Join(dir, "release")
+	script := `#!/bin/sh
+case "$1" in *pci.bus_id*) echo "Field \"pci.bus_id\" is not a valid field to query."; exit 2 ;; esac
+while [ ! -e '` + release + `' ]; do sleep 0.01; done
+echo "NVIDIA GeForce GTX 1060 6GB, 42, 1024, 6144, 55"
+`
+	if err := os.WriteFile(filepath.Join(dir, "nvidia-smi"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	m := &ResourceMonitor{}
+	now := time.Now()
+	if metrics, err := m.refreshNvidiaSMI(now); metrics != nil || err != nil {
+		t.Fatalf("first call = %v, %v; want nothing while nvidia-smi runs", metrics, err)
+	}
+
+	if err := os.WriteFile(release, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		m.nvidiaMu.Lock()
+		running := m.nvidiaRunning
+		m.nvidiaMu.Unlock()
+		if !running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("nvidia-smi didn't finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	metrics, err := m.refreshNvidiaSMI(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics) != 1 || metrics[0].Name != "NVIDIA GeForce GTX 1060 6GB" || metrics[0].UtilPercent != 42 || metrics[0].MemUsedBytes != 1024<<20 {
+		t.Errorf("metrics = %+v", metrics)
+	}
+}
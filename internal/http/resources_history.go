@@ -14,6 +14,27 @@ func cloneHistory(src []HistoryPoint) []HistoryPoint {
 			}
 			out[i].Disks = dm
 		}
+		if h.DiskUtil != nil {
+			du := make(map[string]float64, len(h.DiskUtil))
+			for k, v := range h.DiskUtil {
+				du[k] = v
+			}
+			out[i].DiskUtil = du
+		}
+		if h.Sensors != nil {
+			sm := make(map[string]float64, len(h.Sensors))
+			for k, v := range h.Sensors {
+				sm[k] = v
+			}
+			out[i].Sensors = sm
+		}
+		if h.Pressure != nil {
+			pm := make(map[string]float64, len(h.Pressure))
+			for k, v := range h.Pressure {
+				pm[k] = v
+			}
+			out[i].Pressure = pm
+		}
 	}
 	return out
 }
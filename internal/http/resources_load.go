@@ -0,0 +1,55 @@
+package http
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/load"
+)
+
+func sampleLoadAverage() (*LoadAverage, error) {
+	if runtime.GOOS == "windows" {
+		return nil, nil
+	}
+	avg, err := load.Avg()
+	if err != nil {
+		return nil, err
+	}
+	if avg == nil {
+		return nil, nil
+	}
+	return &LoadAverage{Load1: avg.Load1, Load5: avg.Load5, Load15: avg.Load15}, nil
+}
+
+func (m *ResourceMonitor) samplePerCorePercent() ([]float64, error) {
+	times, err := cpu.Times(true)
+	if err != nil {
+		return nil, err
+	}
+	prev := m.prevCoreTimes
+	m.prevCoreTimes = times
+	if len(prev) != len(times) {
+		// First sample, or CPUs went on/offline.
+		return nil, nil
+	}
+
+	out := make([]float64, len(times))
+	for i := range times {
+		if prev[i].CPU != times[i].CPU {
+			return nil, fmt.Errorf("cpu order changed")
+		}
+		out[i], _ = cpuBusyPercent(prev[i], times[i])
+	}
+	return out, nil
+}
+
+// sampleHostPressure reads /proc/pressure; nil on kernels without PSI or
+// with it disabled (psi=0).
+func sampleHostPressure() *PressureStats {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+	return readPressureFiles(filepath.Join(cgroupFSRoot, "proc/pressure"), "")
+}
@@ -6,6 +6,10 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	psnet "github.com/shirou/gopsutil/v3/net"
 )
 
 const (
@@ -15,19 +19,43 @@ const (
 	cpuDynamicTTLLinux = 2 * time.Second
 	cpuDynamicTTLOther = 5 * time.Second
 	disksSampleTTL     = 5 * time.Second
+	tcpStatesTTL       = 5 * time.Second
+	sensorsSampleTTL   = 2 * time.Second
+	piSampleTTL        = 5 * time.Second
 	gpusSampleTTL      = 5 * time.Second
 	historyMaxAge      = 30 * time.Minute
 	historyMaxPoints   = 2000
 )
 
+type ResourceMonitorOptions struct {
+	// SMART enables drive health reporting via smartctl.
+	SMART bool
+	// ContainerSocket is the Docker/Podman API socket. Empty auto-detects,
+	// "none" disables container reporting.
+	ContainerSocket string
+	// SystemdUnits are always reported, in addition to units linked from
+	// saved links.
+	SystemdUnits []string
+	// Disks selects and labels the mounts in the disks table.
+	Disks DiskRules
+	// NUTUPS are UPSes to query from Network UPS Tools, as ups@host[:port].
+	NUTUPS []string
+}
+
 type ResourceMonitor struct {
+	opts ResourceMonitorOptions
+
 	mu       sync.RWMutex
 	snapshot ResourcesSnapshot
 
 	// CPU percent is derived from deltas between successive samples.
-	prevTotal   float64
-	prevIdle    float64
-	havePrevCPU bool
+	prevCPUTimes  cpu.TimesStat
+	havePrevCPU   bool
+	prevCoreTimes []cpu.TimesStat
+
+	cgroup         *cgroupInfo
+	cgroupDetected bool
+	prevCgroupCPU  *cgroupCPUPrev
 
 	memoryModules       []MemoryModuleInfo
 	memoryModulesLoaded bool
@@ -54,20 +82,90 @@ type ResourceMonitor struct {
 	disksUpdatedAt time.Time
 	disksErr       error
 
+	zpools       map[string]zpoolInfo
+	zfsDatasets  map[string]zfsDatasetInfo
+	zfsUpdatedAt time.Time
+	zfsErr       error
+
+	prevDiskIO   map[string]disk.IOCountersStat
+	prevDiskIOAt time.Time
+
+	smartMu        sync.Mutex
+	smartRunning   bool
+	smartCache     map[string]DiskHealth
+	smartUpdatedAt time.Time
+	smartErr       error
+
+	prevGPUIdle map[string]gpuIdleSample
+
+	nvidiaMu        sync.Mutex
+	nvidiaRunning   bool
+	nvidiaCache     []nvidiaSMIGPU
+	nvidiaUpdatedAt time.Time
+	nvidiaErr       error
+
 	gpusCache     []GPUStats
 	gpusUpdatedAt time.Time
 	gpusErr       error
 
-	prevProcessTimes   map[int32]float64
-	lastProcessSample  time.Time
+	prevNetCounters map[string]psnet.IOCountersStat
+	prevNetAt       time.Time
+
+	tcpStates          map[string]int
+	tcpStatesUpdatedAt time.Time
+	tcpStatesErr       error
+
+	sensorsCache     []SensorReading
+	sensorsUpdatedAt time.Time
+	sensorsErr       error
+
+	piCache     *PiStats
+	piUpdatedAt time.Time
+	piErr       error
+
+	powerCache     *PowerStats
+	powerUpdatedAt time.Time
+	powerErr       error
+
+	upsMu        sync.Mutex
+	upsRunning   bool
+	upsCache     []UPSStats
+	upsUpdatedAt time.Time
+	upsErr       error
+
+	containerAPI        *dockerAPI
+	containerExplicit   bool
+	containersMu        sync.Mutex
+	containersRunning   bool
+	containersCache     []ContainerStats
+	containersUpdatedAt time.Time
+	containersErr       error
+	prevContainerCPU    map[string]containerCPUPrev
+	containerInspect    map[string]containerInspectCache
+
+	// linkedUnits returns the systemd units referenced by saved links.
+	linkedUnits      func() []string
+	systemdCache     []SystemdUnitStatus
+	systemdUpdatedAt time.Time
+	systemdErr       error
+
+	prevProcessTimes  map[int32]float64
+	processStatic     map[int32]processStatic
+	lastProcessSample time.Time
+	// processCPUSampled holds the PIDs whose CPU percent the last sample
+	// measured; new processes have none yet.
+	processCPUSampled  map[int]bool
+	processes          []ProcessInfo
+	processHistory     map[int][]ProcessHistoryPoint
 	boardModel         string
 	boardModelResolved bool
 
 	history []HistoryPoint
 }
 
-func NewResourceMonitor() *ResourceMonitor {
-	return &ResourceMonitor{
+func NewResourceMonitor(opts ResourceMonitorOptions) *ResourceMonitor {
+	m := &ResourceMonitor{
+		opts: opts,
 		snapshot: ResourcesSnapshot{
 			CPU:    CPUStats{Percent: 0},
 			Memory: MemoryStats{},
@@ -75,6 +173,11 @@ func NewResourceMonitor() *ResourceMonitor {
 			Errors: SnapshotError{},
 		},
 	}
+	if socket, explicit := resolveContainerSocket(opts.ContainerSocket); socket != "" {
+		m.containerAPI = newDockerAPI(socket)
+		m.containerExplicit = explicit
+	}
+	return m
 }
 
 func (m *ResourceMonitor) Start(stop <-chan struct{}) {
@@ -118,7 +221,10 @@ func (m *ResourceMonitor) update() {
 		errs.HostIP = m.hostIPErr.Error()
 	}
 
-	cpuPercent, cpuPercentErr := m.sampleCPUPercent()
+	cpuPercent, cpuBreakdown, cpuPercentErr := m.sampleCPUPercent()
+	perCore, perCoreErr := m.samplePerCorePercent()
+	loadAvg, loadErr := sampleLoadAverage()
+	pressure := sampleHostPressure()
 
 	if m.cpuStaticUpdatedAt.IsZero() || now.Sub(m.cpuStaticUpdatedAt) >= cpuStaticTTL {
 		m.cpuStatic, m.cpuStaticErr = sampleCPUStaticInfo()
@@ -147,6 +253,13 @@ func (m *ResourceMonitor) update() {
 		EfficiencyCores:     m.cpuDynamic.EfficiencyCores,
 		PerformanceThreads:  m.cpuDynamic.PerformanceThreads,
 		EfficiencyThreads:   m.cpuDynamic.EfficiencyThreads,
+		Load:                loadAvg,
+		PerCore:             perCore,
+		Breakdown:           cpuBreakdown,
+	}
+	if pressure != nil {
+		cpuStats.Pressure = pressure.CPU
+		cpuStats.IOPressure = pressure.IO
 	}
 
 	var cpuErrs []string
@@ -159,6 +272,12 @@ func (m *ResourceMonitor) update() {
 	if m.cpuDynamicErr != nil {
 		cpuErrs = append(cpuErrs, m.cpuDynamicErr.Error())
 	}
+	if perCoreErr != nil {
+		cpuErrs = append(cpuErrs, fmt.Sprintf("per-core: %v", perCoreErr))
+	}
+	if loadErr != nil {
+		cpuErrs = append(cpuErrs, fmt.Sprintf("load: %v", loadErr))
+	}
 	if len(cpuErrs) > 0 {
 		errs.CPU = strings.Join(cpuErrs, "; ")
 	}
@@ -167,6 +286,14 @@ func (m *ResourceMonitor) update() {
 	if err != nil {
 		errs.Memory = err.Error()
 	}
+	if pressure != nil {
+		memStats.Pressure = pressure.Memory
+	}
+
+	cgroupStats, err := m.sampleCgroup(now, cpuStats.LogicalCores)
+	if err != nil {
+		errs.Cgroup = err.Error()
+	}
 
 	if m.disksUpdatedAt.IsZero() || now.Sub(m.disksUpdatedAt) >= disksSampleTTL {
 		disks, err := m.sampleDisks()
@@ -176,12 +303,28 @@ func (m *ResourceMonitor) update() {
 		m.disksErr = err
 		m.disksUpdatedAt = now
 	}
+	var diskErrs []string
 	if m.disksErr != nil {
-		errs.Disks = m.disksErr.Error()
+		diskErrs = append(diskErrs, m.disksErr.Error())
+	}
+	diskIO, err := m.sampleDiskIO(now)
+	if err != nil {
+		diskErrs = append(diskErrs, fmt.Sprintf("disk io: %v", err))
+	}
+	disks := attachDiskIO(m.disksCache, diskIO)
+	if m.opts.SMART {
+		health, err := m.sampleSMART(now)
+		if err != nil {
+			diskErrs = append(diskErrs, err.Error())
+		}
+		disks = attachDiskHealth(disks, health)
+	}
+	if len(diskErrs) > 0 {
+		errs.Disks = strings.Join(diskErrs, "; ")
 	}
 
 	if m.gpusUpdatedAt.IsZero() || now.Sub(m.gpusUpdatedAt) >= gpusSampleTTL {
-		gpus, err := m.sampleGPUs()
+		gpus, err := m.sampleGPUs(now)
 		if gpus != nil || err == nil {
 			m.gpusCache = gpus
 		}
@@ -192,40 +335,146 @@ func (m *ResourceMonitor) update() {
 		errs.GPUs = m.gpusErr.Error()
 	}
 
+	netStats, err := m.sampleNetwork(now)
+	var netErrs []string
+	if err != nil {
+		netErrs = append(netErrs, err.Error())
+	}
+	if m.tcpStatesUpdatedAt.IsZero() || now.Sub(m.tcpStatesUpdatedAt) >= tcpStatesTTL {
+		states, err := sampleTCPStates()
+		if states != nil || err == nil {
+			m.tcpStates = states
+		}
+		m.tcpStatesErr = err
+		m.tcpStatesUpdatedAt = now
+	}
+	if m.tcpStatesErr != nil {
+		netErrs = append(netErrs, fmt.Sprintf("tcp: %v", m.tcpStatesErr))
+	}
+	netStats.TCP = m.tcpStates
+	if len(netErrs) > 0 {
+		errs.Network = strings.Join(netErrs, "; ")
+	}
+
+	if m.sensorsUpdatedAt.IsZero() || now.Sub(m.sensorsUpdatedAt) >= sensorsSampleTTL {
+		sensors, err := sampleSensors()
+		if sensors != nil || err == nil {
+			m.sensorsCache = sensors
+		}
+		m.sensorsErr = err
+		m.sensorsUpdatedAt = now
+	}
+	if m.sensorsErr != nil {
+		errs.Sensors = m.sensorsErr.Error()
+	}
+
+	if model := m.boardModelName(); isRaspberryPi(model) {
+		if m.piUpdatedAt.IsZero() || now.Sub(m.piUpdatedAt) >= piSampleTTL {
+			m.piCache, m.piErr = samplePi(model)
+			m.piUpdatedAt = now
+		}
+		if m.piErr != nil {
+			errs.Pi = m.piErr.Error()
+		}
+	}
+
+	if m.powerUpdatedAt.IsZero() || now.Sub(m.powerUpdatedAt) >= powerSampleTTL {
+		power, err := m.samplePower(now)
+		if power != nil || err == nil {
+			m.powerCache = power
+		}
+		m.powerErr = err
+		m.powerUpdatedAt = now
+	}
+	if m.powerErr != nil {
+		errs.Power = m.powerErr.Error()
+	}
+
+	containers, containersErr := m.refreshContainers(now, m.hostIP)
+	// An auto-detected socket we can't talk to (e.g. no permission) is
+	// treated as "no containers" rather than an error.
+	if containersErr != nil && (m.containerExplicit || containers != nil) {
+		errs.Containers = containersErr.Error()
+	}
+
+	if m.systemdUpdatedAt.IsZero() || now.Sub(m.systemdUpdatedAt) >= systemdSampleTTL {
+		units, err := sampleSystemdUnits(m.systemdUnits())
+		if units != nil || err == nil {
+			m.systemdCache = units
+		}
+		m.systemdErr = err
+		m.systemdUpdatedAt = now
+	}
+	if m.systemdErr != nil {
+		errs.Systemd = m.systemdErr.Error()
+	}
+
 	procCount, procErr := sampleProcessCount()
 	if procErr != nil {
 		errs.CPU = strings.TrimSpace(strings.Join([]string{errs.CPU, fmt.Sprintf("processes: %v", procErr)}, "; "))
 	}
 
-	topCPU, topMem, topProcErr := m.sampleTopProcesses(now, cpuStats.LogicalCores, memStats.TotalBytes)
-	if topProcErr != nil {
-		errs.CPU = strings.TrimSpace(strings.Join([]string{errs.CPU, fmt.Sprintf("top processes: %v", topProcErr)}, "; "))
+	procs, procsErr := m.sampleProcesses(now, cpuStats.LogicalCores, memStats.TotalBytes)
+	if procsErr != nil {
+		errs.CPU = strings.TrimSpace(strings.Join([]string{errs.CPU, fmt.Sprintf("top processes: %v", procsErr)}, "; "))
 	}
+	topCPU, topMem := topProcesses(procs, m.processCPUSampled)
 
 	snap := ResourcesSnapshot{
-		HostIP:    m.hostIP,
-		UpdatedAt: now.UnixMilli(),
-		CPU:       cpuStats,
-		Memory:    memStats,
-		Disks:     m.disksCache,
-		GPUs:      m.gpusCache,
-		Processes: procCount,
-		TopCPU:    topCPU,
-		TopMemory: topMem,
-		Errors:    errs,
+		HostIP:     m.hostIP,
+		UpdatedAt:  now.UnixMilli(),
+		CPU:        cpuStats,
+		Memory:     memStats,
+		Cgroup:     cgroupStats,
+		Disks:      disks,
+		GPUs:       m.gpusCache,
+		Network:    netStats,
+		Sensors:    m.sensorsCache,
+		Pi:         m.piCache,
+		Power:      m.powerCache,
+		Containers: containers,
+		Systemd:    m.systemdCache,
+		Processes:  procCount,
+		TopCPU:     topCPU,
+		TopMemory:  topMem,
+		Errors:     errs,
 	}
 
 	m.mu.Lock()
 	m.snapshot = snap
 	m.appendHistoryLocked(snap)
+	if procsErr == nil {
+		m.processes = procs
+		m.appendProcessHistoryLocked(now, procs)
+	}
 	m.mu.Unlock()
 }
 
 func (m *ResourceMonitor) appendHistoryLocked(snap ResourcesSnapshot) {
 	hp := HistoryPoint{
-		Time: snap.UpdatedAt,
-		CPU:  snap.CPU.Percent,
-		Mem:  snap.Memory.UsedPercent,
+		Time:    snap.UpdatedAt,
+		CPU:     snap.CPU.Percent,
+		Mem:     snap.Memory.UsedPercent,
+		NetRx:   snap.Network.RxBytesPerSec,
+		NetTx:   snap.Network.TxBytesPerSec,
+		Sensors: sensorHistoryValues(snap.Sensors),
+	}
+	if snap.CPU.Load != nil {
+		load1 := snap.CPU.Load.Load1
+		hp.Load1 = &load1
+	}
+	if b := snap.CPU.Breakdown; b != nil {
+		hp.IOWait = b.IOWait
+		hp.Steal = b.Steal
+	}
+	for name, p := range map[string]*PressureStat{"cpu": snap.CPU.Pressure, "memory": snap.Memory.Pressure, "io": snap.CPU.IOPressure} {
+		if p == nil {
+			continue
+		}
+		if hp.Pressure == nil {
+			hp.Pressure = make(map[string]float64)
+		}
+		hp.Pressure[name] = p.Some.Avg10
 	}
 
 	for _, d := range snap.Disks {
@@ -236,6 +485,13 @@ func (m *ResourceMonitor) appendHistoryLocked(snap ResourcesSnapshot) {
 			hp.Disks = make(map[string]float64)
 		}
 		hp.Disks[d.Mountpoint] = d.UsedPercent
+
+		if d.IO != nil && d.IO.Device != "" {
+			if hp.DiskUtil == nil {
+				hp.DiskUtil = make(map[string]float64)
+			}
+			hp.DiskUtil[d.IO.Device] = d.IO.UtilPercent
+		}
 	}
 
 	m.history = append(m.history, hp)
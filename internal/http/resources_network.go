@@ -0,0 +1,173 @@
+package http
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	psnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// tcpStateNames maps the hex state column of /proc/net/tcp{,6} to the names
+// gopsutil reports on other platforms.
+var tcpStateNames = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+}
+
+// sampleNetwork computes per-interface rates from the counter deltas between
+// successive calls, the same way sampleCPUPercent does for CPU time.
+func (m *ResourceMonitor) sampleNetwork(now time.Time) (NetworkStats, error) {
+	counters, err := psnet.IOCounters(true)
+	if err != nil {
+		return NetworkStats{}, err
+	}
+
+	ifaceInfo := make(map[string]net.Interface)
+	if ifaces, err := net.Interfaces(); err == nil {
+		for _, iface := range ifaces {
+			ifaceInfo[iface.Name] = iface
+		}
+	}
+
+	elapsedSec := now.Sub(m.prevNetAt).Seconds()
+	havePrev := m.prevNetCounters != nil && elapsedSec > 0
+
+	stats := NetworkStats{}
+	newPrev := make(map[string]psnet.IOCountersStat, len(counters))
+	for _, c := range counters {
+		newPrev[c.Name] = c
+
+		ns := NetInterfaceStats{
+			Name:      c.Name,
+			RxBytes:   c.BytesRecv,
+			TxBytes:   c.BytesSent,
+			RxErrors:  c.Errin,
+			TxErrors:  c.Errout,
+			RxDropped: c.Dropin,
+			TxDropped: c.Dropout,
+		}
+		if iface, ok := ifaceInfo[c.Name]; ok {
+			ns.Up = iface.Flags&net.FlagUp != 0
+			ns.Loopback = iface.Flags&net.FlagLoopback != 0
+			ns.MAC = iface.HardwareAddr.String()
+			if addrs, err := iface.Addrs(); err == nil {
+				for _, a := range addrs {
+					ns.Addresses = append(ns.Addresses, a.String())
+				}
+			}
+		}
+		if runtime.GOOS == "linux" {
+			if speed, err := readIntFromFile("/sys/class/net/" + c.Name + "/speed"); err == nil && speed > 0 {
+				ns.SpeedMbps = int(speed)
+			}
+		}
+
+		if prev, ok := m.prevNetCounters[c.Name]; ok && havePrev {
+			ns.RxBytesPerSec = counterRate(prev.BytesRecv, c.BytesRecv, elapsedSec)
+			ns.TxBytesPerSec = counterRate(prev.BytesSent, c.BytesSent, elapsedSec)
+			ns.RxPacketsPerSec = counterRate(prev.PacketsRecv, c.PacketsRecv, elapsedSec)
+			ns.TxPacketsPerSec = counterRate(prev.PacketsSent, c.PacketsSent, elapsedSec)
+			ns.ErrorsPerSec = counterRate(prev.Errin+prev.Errout, c.Errin+c.Errout, elapsedSec)
+			ns.DropsPerSec = counterRate(prev.Dropin+prev.Dropout, c.Dropin+c.Dropout, elapsedSec)
+		}
+
+		if !ns.Loopback {
+			stats.RxBytesPerSec += ns.RxBytesPerSec
+			stats.TxBytesPerSec += ns.TxBytesPerSec
+		}
+		stats.Interfaces = append(stats.Interfaces, ns)
+	}
+	sort.Slice(stats.Interfaces, func(i, j int) bool { return stats.Interfaces[i].Name < stats.Interfaces[j].Name })
+
+	m.prevNetCounters = newPrev
+	m.prevNetAt = now
+	return stats, nil
+}
+
+func counterRate(prev, cur uint64, elapsedSec float64) float64 {
+	// Counters reset when an interface is re-created; treat that as no traffic.
+	if cur < prev || elapsedSec <= 0 {
+		return 0
+	}
+	return float64(cur-prev) / elapsedSec
+}
+
+func sampleTCPStates() (map[string]int, error) {
+	counts := make(map[string]int)
+	if runtime.GOOS == "linux" {
+		var errs []string
+		var read int
+		for _, p := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+			f, err := os.Open(p)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					errs = append(errs, err.Error())
+				}
+				continue
+			}
+			err = parseProcNetTCP(f, counts)
+			f.Close()
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", p, err))
+				continue
+			}
+			read++
+		}
+		if read == 0 && len(errs) > 0 {
+			return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+		}
+		return counts, nil
+	}
+
+	conns, err := psnet.ConnectionsWithoutUids("tcp")
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range conns {
+		state := strings.ToUpper(strings.TrimSpace(c.Status))
+		if state == "" || state == "NONE" {
+			continue
+		}
+		counts[state]++
+	}
+	return counts, nil
+}
+
+// parseProcNetTCP adds the connection states listed in a /proc/net/tcp
+// style table to counts.
+func parseProcNetTCP(r io.Reader, counts map[string]int) error {
+	scanner := bufio.NewScanner(r)
+	first := true
+	for scanner.Scan() {
+		if first {
+			first = false
+			continue
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		state, ok := tcpStateNames[strings.ToUpper(fields[3])]
+		if !ok {
+			state = "UNKNOWN"
+		}
+		counts[state]++
+	}
+	return scanner.Err()
+}
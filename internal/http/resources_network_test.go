@@ -0,0 +1,55 @@
+package http
+
+import (
+	"bytes"
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestParseProcNetTCP(t *testing.T) {
+	counts := make(map[string]int)
+	// Both tables add to the same counts, as sampleTCPStates reads them.
+	for _, name := range []string{"tcp", "tcp6"} {
+		if err := parseProcNetTCP(bytes.NewReader(readFixture(t, "proc", "net", name)), counts); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	want := map[string]int{
+		"LISTEN":      3,
+		"ESTABLISHED": 3,
+		"TIME_WAIT":   1,
+		"CLOSE_WAIT":  1,
+		// 0C is the kernel's NEW_SYN_RECV, which has no portable name.
+		"UNKNOWN": 1,
+	}
+	if !maps.Equal(counts, want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+
+	// The header and truncated lines aren't connections.
+	counts = make(map[string]int)
+	table := "  sl  local_address rem_address   st\n   0: 0100007F:0CEA\n\n"
+	if err := parseProcNetTCP(strings.NewReader(table), counts); err != nil || len(counts) != 0 {
+		t.Errorf("header only: counts = %v, err = %v", counts, err)
+	}
+}
+
+func TestCounterRate(t *testing.T) {
+	tests := []struct {
+		prev, cur uint64
+		elapsed   float64
+		want      float64
+	}{
+		{prev: 1000, cur: 3000, elapsed: 2, want: 1000},
+		{prev: 1000, cur: 1000, elapsed: 2, want: 0},
+		// The interface was re-created and its counters restarted.
+		{prev: 5000, cur: 200, elapsed: 2, want: 0},
+		{prev: 1000, cur: 3000, elapsed: 0, want: 0},
+	}
+	for _, tt := range tests {
+		if got := counterRate(tt.prev, tt.cur, tt.elapsed); got != tt.want {
+			t.Errorf("counterRate(%d, %d, %v) = %v, want %v", tt.prev, tt.cur, tt.elapsed, got, tt.want)
+		}
+	}
+}
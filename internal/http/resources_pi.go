@@ -0,0 +1,147 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bits of the firmware's get_throttled mask.
+const (
+	piUnderVoltageNow       = 1 << 0
+	piFreqCappedNow         = 1 << 1
+	piThrottledNow          = 1 << 2
+	piSoftTempLimitNow      = 1 << 3
+	piUnderVoltageOccurred  = 1 << 16
+	piFreqCappedOccurred    = 1 << 17
+	piThrottledOccurred     = 1 << 18
+	piSoftTempLimitOccurred = 1 << 19
+)
+
+const piThrottledSysfs = "devices/platform/soc/soc:firmware/get_throttled"
+
+func isRaspberryPi(model string) bool {
+	return strings.Contains(strings.ToLower(model), "raspberry pi")
+}
+
+func samplePi(model string) (*PiStats, error) {
+	stats := &PiStats{Model: model}
+	var warnings []string
+
+	vcgencmd, lookErr := exec.LookPath("vcgencmd")
+	if lookErr == nil {
+		if out, err := runVcgencmd(vcgencmd, "get_throttled"); err != nil {
+			warnings = append(warnings, fmt.Sprintf("get_throttled: %v", err))
+		} else if mask, err := parseThrottled(out); err != nil {
+			warnings = append(warnings, fmt.Sprintf("get_throttled: %v", err))
+		} else {
+			setPiThrottled(stats, mask, "vcgencmd")
+		}
+
+		if out, err := runVcgencmd(vcgencmd, "measure_volts", "core"); err == nil {
+			if v, err := parseVcgencmdVolts(out); err == nil {
+				stats.CoreVolts = &v
+			}
+		}
+		if out, err := runVcgencmd(vcgencmd, "measure_clock", "arm"); err == nil {
+			if hz, err := parseVcgencmdClock(out); err == nil && hz > 0 {
+				mhz := hz / 1e6
+				stats.ARMClockMHz = &mhz
+			}
+		}
+		// The GPU clock is "core" on Pi 1-4 and "v3d" for the 3D block; prefer v3d.
+		for _, clock := range []string{"v3d", "core"} {
+			out, err := runVcgencmd(vcgencmd, "measure_clock", clock)
+			if err != nil {
+				continue
+			}
+			if hz, err := parseVcgencmdClock(out); err == nil && hz > 0 {
+				mhz := hz / 1e6
+				stats.GPUClockMHz = &mhz
+				break
+			}
+		}
+	}
+
+	if stats.ThrottledRaw == "" {
+		raw := readTrimmedFile(filepath.Join(sysfsRoot, piThrottledSysfs))
+		if raw != "" {
+			mask, err := parseThrottled(raw)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("get_throttled sysfs: %v", err))
+			} else {
+				setPiThrottled(stats, mask, "sysfs")
+			}
+		} else if lookErr != nil {
+			warnings = append(warnings, "vcgencmd not found and firmware get_throttled unavailable")
+		}
+	}
+
+	if len(warnings) > 0 {
+		return stats, fmt.Errorf("%s", strings.Join(warnings, "; "))
+	}
+	return stats, nil
+}
+
+func setPiThrottled(s *PiStats, mask uint64, source string) {
+	s.Source = source
+	s.ThrottledRaw = fmt.Sprintf("0x%x", mask)
+	s.UnderVoltage = mask&piUnderVoltageNow != 0
+	s.FreqCapped = mask&piFreqCappedNow != 0
+	s.Throttled = mask&piThrottledNow != 0
+	s.SoftTempLimit = mask&piSoftTempLimitNow != 0
+	s.UnderVoltageOccurred = mask&piUnderVoltageOccurred != 0
+	s.FreqCappedOccurred = mask&piFreqCappedOccurred != 0
+	s.ThrottledOccurred = mask&piThrottledOccurred != 0
+	s.SoftTempLimitOccurred = mask&piSoftTempLimitOccurred != 0
+	s.Healthy = mask&(piUnderVoltageNow|piFreqCappedNow|piThrottledNow|piSoftTempLimitNow) == 0
+}
+
+func runVcgencmd(path string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, args...).CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// parseThrottled accepts both `vcgencmd get_throttled` output
+// ("throttled=0x50005") and the bare hex of the sysfs file ("50005").
+func parseThrottled(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "throttled=")
+	s = strings.TrimPrefix(strings.ToLower(s), "0x")
+	if s == "" {
+		return 0, fmt.Errorf("empty throttled value")
+	}
+	return strconv.ParseUint(s, 16, 32)
+}
+
+// parseVcgencmdVolts parses "volt=0.8563V".
+func parseVcgencmdVolts(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	_, v, ok := strings.Cut(s, "=")
+	if !ok {
+		return 0, fmt.Errorf("unexpected measure_volts output %q", s)
+	}
+	return strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(v), "V"), 64)
+}
+
+// parseVcgencmdClock parses "frequency(48)=1500398464" into Hz.
+func parseVcgencmdClock(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	_, v, ok := strings.Cut(s, "=")
+	if !ok || !strings.HasPrefix(s, "frequency") {
+		return 0, fmt.Errorf("unexpected measure_clock output %q", s)
+	}
+	return strconv.ParseFloat(strings.TrimSpace(v), 64)
+}
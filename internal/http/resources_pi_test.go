@@ -0,0 +1,92 @@
+package http
+
+import "testing"
+
+func TestParseThrottled(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint64
+		wantErr bool
+	}{
+		{"throttled=0x0\n", 0, false},
+		// Under-voltage now, and under-voltage and throttling since boot.
+		{"throttled=0x50005\n", 0x50005, false},
+		{"throttled=0xe0000", 0xe0000, false},
+		{"throttled=0x80008", 0x80008, false},
+		// The sysfs file has no prefix.
+		{"50005\n", 0x50005, false},
+		{"0", 0, false},
+		{"throttled=", 0, true},
+		{"", 0, true},
+		{`error=1 error_msg="Command not registered"`, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseThrottled(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseThrottled(%q) = %#x, %v; want %#x, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSetPiThrottled(t *testing.T) {
+	var s PiStats
+	setPiThrottled(&s, 0x50005, "vcgencmd")
+	if s.ThrottledRaw != "0x50005" || s.Source != "vcgencmd" {
+		t.Errorf("raw = %q, source = %q", s.ThrottledRaw, s.Source)
+	}
+	if !s.UnderVoltage || !s.Throttled || s.FreqCapped || s.SoftTempLimit || s.Healthy {
+		t.Errorf("current flags = %+v", s)
+	}
+	if !s.UnderVoltageOccurred || !s.ThrottledOccurred || s.FreqCappedOccurred || s.SoftTempLimitOccurred {
+		t.Errorf("occurred flags = %+v", s)
+	}
+
+	// Only past events: healthy now.
+	s = PiStats{}
+	setPiThrottled(&s, 0xe0000, "sysfs")
+	if !s.Healthy || s.UnderVoltageOccurred || !s.FreqCappedOccurred || !s.ThrottledOccurred || !s.SoftTempLimitOccurred {
+		t.Errorf("flags = %+v", s)
+	}
+}
+
+func TestParseVcgencmdVolts(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"volt=0.8563V\n", 0.8563, false},
+		{"volt=1.2000V", 1.2, false},
+		// Pi 5 reads the PMIC.
+		{"volt=0.7200V", 0.72, false},
+		{"0.8563V", 0, true},
+		{`error=2 error_msg="Invalid arguments"`, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseVcgencmdVolts(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseVcgencmdVolts(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseVcgencmdClock(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"frequency(48)=1500398464\n", 1500398464, false},
+		{"frequency(0)=2400033792", 2400033792, false},
+		// Clocks that are gated off read 0; samplePi skips them.
+		{"frequency(46)=0", 0, false},
+		{"1500398464", 0, true},
+		{`error=2 error_msg="Invalid arguments"`, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseVcgencmdClock(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseVcgencmdClock(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package http
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	powerSampleTTL = 10 * time.Second
+	nutDefaultPort = "3493"
+	nutTimeout     = 2 * time.Second
+)
+
+// readPowerSupplies reads batteries and AC adapters from
+// <root>/class/power_supply. Peripheral batteries (mice, keyboards) are
+// skipped.
+func readPowerSupplies(root string) (*PowerStats, error) {
+	base := filepath.Join(root, "class", "power_supply")
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	stats := &PowerStats{}
+	for _, e := range entries {
+		ev := readUevent(filepath.Join(base, e.Name(), "uevent"))
+		if ev["POWER_SUPPLY_SCOPE"] == "Device" {
+			continue
+		}
+		switch ev["POWER_SUPPLY_TYPE"] {
+		case "Mains", "USB", "USB_C", "USB_PD":
+			if v, ok := ev["POWER_SUPPLY_ONLINE"]; ok {
+				online := v == "1"
+				// Any online adapter means we're on AC.
+				if stats.ACOnline == nil || online {
+					stats.ACOnline = &online
+				}
+			}
+		case "Battery":
+			stats.Batteries = append(stats.Batteries, batteryFromUevent(e.Name(), ev))
+		}
+	}
+	sort.Slice(stats.Batteries, func(i, j int) bool { return stats.Batteries[i].Name < stats.Batteries[j].Name })
+
+	if stats.ACOnline == nil && len(stats.Batteries) == 0 {
+		return nil, nil
+	}
+	return stats, nil
+}
+
+func batteryFromUevent(name string, ev map[string]string) BatteryStats {
+	num := func(key string) (float64, bool) {
+		v, err := strconv.ParseFloat(ev["POWER_SUPPLY_"+key], 64)
+		return v, err == nil
+	}
+
+	b := BatteryStats{
+		Name:   name,
+		Model:  strings.TrimSpace(ev["POWER_SUPPLY_MANUFACTURER"] + " " + ev["POWER_SUPPLY_MODEL_NAME"]),
+		Status: ev["POWER_SUPPLY_STATUS"],
+	}
+
+	// Batteries report either energy (µWh, with power in µW) or charge
+	// (µAh, with current in µA); the ratios work the same way.
+	now, okNow := num("ENERGY_NOW")
+	full, okFull := num("ENERGY_FULL")
+	design, okDesign := num("ENERGY_FULL_DESIGN")
+	rate, okRate := num("POWER_NOW")
+	if !okNow {
+		now, okNow = num("CHARGE_NOW")
+		full, okFull = num("CHARGE_FULL")
+		design, okDesign = num("CHARGE_FULL_DESIGN")
+		rate, okRate = num("CURRENT_NOW")
+		if volts, ok := num("VOLTAGE_NOW"); ok && okRate {
+			w := rate * volts / 1e12
+			b.PowerWatts = &w
+		}
+	} else if okRate {
+		w := rate / 1e6
+		b.PowerWatts = &w
+	}
+
+	if v, ok := num("CAPACITY"); ok {
+		b.Percent = &v
+	} else if okNow && okFull && full > 0 {
+		pct := clampPercent(now / full * 100)
+		b.Percent = &pct
+	}
+	if okFull && okDesign && design > 0 {
+		health := full / design * 100
+		b.HealthPercent = &health
+	}
+
+	if okNow && okRate && rate > 0 {
+		var hours float64
+		switch b.Status {
+		case "Discharging":
+			hours = now / rate
+		case "Charging":
+			if okFull && full > now {
+				hours = (full - now) / rate
+			}
+		}
+		if hours > 0 {
+			sec := int64(hours * 3600)
+			b.TimeRemainingSec = &sec
+		}
+	}
+	return b
+}
+
+// parseNUTTarget splits "ups@host[:port]" (host defaults to localhost).
+func parseNUTTarget(target string) (ups, addr string, err error) {
+	ups, host, _ := strings.Cut(strings.TrimSpace(target), "@")
+	if ups == "" {
+		return "", "", fmt.Errorf("invalid NUT target %q, expected ups@host[:port]", target)
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), nutDefaultPort)
+	}
+	return ups, host, nil
+}
+
+// queryNUT asks upsd for all variables of one UPS using the plain-text
+// protocol ("LIST VAR <ups>").
+func queryNUT(addr, ups string) (map[string]string, error) {
+	conn, err := net.DialTimeout("tcp", addr, nutTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(nutTimeout))
+
+	if _, err := fmt.Fprintf(conn, "LIST VAR %s\n", ups); err != nil {
+		return nil, err
+	}
+	vars, err := parseNUTListVar(bufio.NewReader(conn), ups)
+	_, _ = fmt.Fprint(conn, "LOGOUT\n")
+	return vars, err
+}
+
+// parseNUTListVar reads a LIST VAR response up to its END line:
+//
+//	BEGIN LIST VAR ups
+//	VAR ups battery.charge "100"
+//	END LIST VAR ups
+func parseNUTListVar(r *bufio.Reader, ups string) (map[string]string, error) {
+	vars := make(map[string]string)
+	for {
+		line, err := r.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "ERR ") {
+			return nil, fmt.Errorf("upsd: %s", strings.TrimPrefix(line, "ERR "))
+		}
+		if line == "END LIST VAR "+ups {
+			return vars, nil
+		}
+		if rest, ok := strings.CutPrefix(line, "VAR "+ups+" "); ok {
+			key, value, _ := strings.Cut(rest, " ")
+			if unq, err := strconv.Unquote(value); err == nil {
+				value = unq
+			}
+			vars[key] = value
+		}
+		if err != nil {
+			return nil, fmt.Errorf("upsd: %w", err)
+		}
+	}
+}
+
+// refreshUPS queries upsd in the background, like sampleSMART, since an
+// unreachable UPS takes the full nutTimeout, and returns the last completed
+// result.
+func (m *ResourceMonitor) refreshUPS(now time.Time) ([]UPSStats, error) {
+	if len(m.opts.NUTUPS) == 0 {
+		return nil, nil
+	}
+	m.upsMu.Lock()
+	defer m.upsMu.Unlock()
+
+	if !m.upsRunning && (m.upsUpdatedAt.IsZero() || now.Sub(m.upsUpdatedAt) >= powerSampleTTL) {
+		m.upsRunning = true
+		m.upsUpdatedAt = now
+		go func() {
+			ups, err := sampleNUT(m.opts.NUTUPS)
+			m.upsMu.Lock()
+			defer m.upsMu.Unlock()
+			m.upsCache = ups
+			m.upsErr = err
+			m.upsRunning = false
+		}()
+	}
+	return m.upsCache, m.upsErr
+}
+
+// sampleNUT queries all targets in parallel and returns the reachable ones
+// in configured order.
+func sampleNUT(targets []string) ([]UPSStats, error) {
+	results := make([]*UPSStats, len(targets))
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ups, addr, err := parseNUTTarget(target)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			vars, err := queryNUT(addr, ups)
+			if err != nil {
+				errs[i] = fmt.Errorf("nut %s: %w", target, err)
+				return
+			}
+			u := upsFromNUTVars(target, vars)
+			results[i] = &u
+		}()
+	}
+	wg.Wait()
+
+	var out []UPSStats
+	var msgs []string
+	for i, u := range results {
+		if u != nil {
+			out = append(out, *u)
+		}
+		if errs[i] != nil {
+			msgs = append(msgs, errs[i].Error())
+		}
+	}
+	if len(msgs) > 0 {
+		return out, fmt.Errorf("%s", strings.Join(msgs, "; "))
+	}
+	return out, nil
+}
+
+func upsFromNUTVars(name string, vars map[string]string) UPSStats {
+	num := func(key string) *float64 {
+		v, err := strconv.ParseFloat(vars[key], 64)
+		if err != nil {
+			return nil
+		}
+		return &v
+	}
+
+	u := UPSStats{
+		Name:          name,
+		Model:         strings.TrimSpace(vars["ups.mfr"] + " " + vars["ups.model"]),
+		Status:        vars["ups.status"],
+		ChargePercent: num("battery.charge"),
+		LoadPercent:   num("ups.load"),
+		InputVoltage:  num("input.voltage"),
+	}
+	if u.Model == "" {
+		u.Model = strings.TrimSpace(vars["device.mfr"] + " " + vars["device.model"])
+	}
+	for _, flag := range strings.Fields(u.Status) {
+		switch flag {
+		case "OB":
+			u.OnBattery = true
+		case "LB":
+			u.LowBattery = true
+		}
+	}
+	if rt := num("battery.runtime"); rt != nil {
+		sec := int64(*rt)
+		u.RuntimeSec = &sec
+	}
+	return u
+}
+
+func (m *ResourceMonitor) samplePower(now time.Time) (*PowerStats, error) {
+	var errs []string
+	stats, err := readPowerSupplies(sysfsRoot)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	ups, err := m.refreshUPS(now)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(ups) > 0 {
+		if stats == nil {
+			stats = &PowerStats{}
+		}
+		stats.UPS = ups
+	}
+	// An AC adapter alone (desktops) isn't worth a card.
+	if stats != nil && len(stats.Batteries) == 0 && len(stats.UPS) == 0 {
+		stats = nil
+	}
+
+	if len(errs) > 0 {
+		return stats, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return stats, nil
+}
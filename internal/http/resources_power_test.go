@@ -0,0 +1,122 @@
+package http
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeUpsd answers LIST VAR for the UPS "myups" like upsd does and ERR
+// UNKNOWN-UPS for anything else.
+func fakeUpsd(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch cmd := strings.TrimSpace(line); cmd {
+					case "LIST VAR myups":
+						fmt.Fprint(conn, "BEGIN LIST VAR myups\n"+
+							"VAR myups battery.charge \"87\"\n"+
+							"VAR myups battery.runtime \"1520\"\n"+
+							"VAR myups device.mfr \"CPS\"\n"+
+							"VAR myups device.model \"CP1500PFCLCD\"\n"+
+							"VAR myups input.voltage \"231.0\"\n"+
+							"VAR myups ups.load \"23\"\n"+
+							"VAR myups ups.status \"OB LB\"\n"+
+							"END LIST VAR myups\n")
+					case "LOGOUT":
+						fmt.Fprint(conn, "OK Goodbye\n")
+						return
+					default:
+						if strings.HasPrefix(cmd, "LIST VAR ") {
+							fmt.Fprint(conn, "ERR UNKNOWN-UPS\n")
+						}
+					}
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestQueryNUT(t *testing.T) {
+	addr := fakeUpsd(t)
+	vars, err := queryNUT(addr, "myups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := upsFromNUTVars("myups@"+addr, vars)
+	if u.Model != "CPS CP1500PFCLCD" || u.Status != "OB LB" || !u.OnBattery || !u.LowBattery {
+		t.Errorf("ups = %+v", u)
+	}
+	if u.ChargePercent == nil || *u.ChargePercent != 87 {
+		t.Errorf("ChargePercent = %v, want 87", u.ChargePercent)
+	}
+	if u.RuntimeSec == nil || *u.RuntimeSec != 1520 {
+		t.Errorf("RuntimeSec = %v, want 1520", u.RuntimeSec)
+	}
+	if u.InputVoltage == nil || *u.InputVoltage != 231 {
+		t.Errorf("InputVoltage = %v, want 231", u.InputVoltage)
+	}
+
+	if _, err := queryNUT(addr, "other"); err == nil || !strings.Contains(err.Error(), "UNKNOWN-UPS") {
+		t.Errorf("unknown ups err = %v, want UNKNOWN-UPS", err)
+	}
+}
+
+func TestSampleNUTKeepsReachableUPSes(t *testing.T) {
+	addr := fakeUpsd(t)
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := closed.Addr().String()
+	_ = closed.Close()
+
+	ups, err := sampleNUT([]string{"myups@" + addr, "other@" + addr, "myups@" + down})
+	if len(ups) != 1 || ups[0].Name != "myups@"+addr {
+		t.Errorf("ups = %+v, want only the reachable one", ups)
+	}
+	if err == nil || !strings.Contains(err.Error(), "other@") || !strings.Contains(err.Error(), down) {
+		t.Errorf("err = %v, want errors for both failing targets", err)
+	}
+}
+
+func TestRefreshUPSDoesNotBlock(t *testing.T) {
+	// Accepts but never answers, so queryNUT waits for nutTimeout.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	m := &ResourceMonitor{opts: ResourceMonitorOptions{NUTUPS: []string{"myups@" + l.Addr().String()}}}
+	start := time.Now()
+	ups, err := m.refreshUPS(start)
+	if elapsed := time.Since(start); elapsed > nutTimeout/2 {
+		t.Errorf("refreshUPS took %v, want it to return while upsd is queried", elapsed)
+	}
+	if ups != nil || err != nil {
+		t.Errorf("first refresh = %v, %v; want no result yet", ups, err)
+	}
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// parsePressure parses a PSI file (/proc/pressure/* or a cgroup's
+// *.pressure):
+//
+//	some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func parsePressure(r io.Reader) *PressureStat {
+	var out PressureStat
+	found := false
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		var avg PressureAvg
+		for _, f := range fields[1:] {
+			k, v, ok := strings.Cut(f, "=")
+			if !ok {
+				continue
+			}
+			switch k {
+			case "avg10":
+				avg.Avg10, _ = strconv.ParseFloat(v, 64)
+			case "avg60":
+				avg.Avg60, _ = strconv.ParseFloat(v, 64)
+			case "avg300":
+				avg.Avg300, _ = strconv.ParseFloat(v, 64)
+			case "total":
+				avg.TotalMicros, _ = strconv.ParseUint(v, 10, 64)
+			}
+		}
+		switch fields[0] {
+		case "some":
+			out.Some = avg
+			found = true
+		case "full":
+			full := avg
+			out.Full = &full
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	return &out
+}
+
+// readPressureFiles reads cpu, memory and io pressure from dir, with files
+// named resource+suffix ("cpu.pressure" in a cgroup, plain "cpu" under
+// /proc/pressure). Returns nil if none are readable.
+func readPressureFiles(dir, suffix string) *PressureStats {
+	read := func(name string) *PressureStat {
+		f, err := os.Open(filepath.Join(dir, name+suffix))
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+		return parsePressure(f)
+	}
+	ps := PressureStats{
+		CPU:    read("cpu"),
+		Memory: read("memory"),
+		IO:     read("io"),
+	}
+	if ps.CPU == nil && ps.Memory == nil && ps.IO == nil {
+		return nil
+	}
+	return &ps
+}
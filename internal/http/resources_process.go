@@ -4,11 +4,26 @@ import (
 	"context"
 	"math"
 	"runtime"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/shirou/gopsutil/v3/process"
 )
 
+const (
+	processHistoryTopN   = 10
+	processHistoryMaxAge = 5 * time.Minute
+)
+
+// processStatic holds per-process fields that don't change during its
+// lifetime, so they are only looked up once per PID.
+type processStatic struct {
+	createTime int64
+	user       string
+	cmdline    string
+}
+
 func sampleProcessCount() (int, error) {
 	pids, err := process.Pids()
 	if err != nil {
@@ -17,17 +32,19 @@ func sampleProcessCount() (int, error) {
 	return len(pids), nil
 }
 
-func (m *ResourceMonitor) sampleTopProcesses(now time.Time, logicalCores int, memTotal uint64) (*ProcessSample, *ProcessSample, error) {
+func (m *ResourceMonitor) sampleProcesses(now time.Time, logicalCores int, memTotal uint64) ([]ProcessInfo, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	procs, err := process.ProcessesWithContext(ctx)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	elapsedSec := now.Sub(m.lastProcessSample).Seconds()
 	newPrev := make(map[int32]float64, len(procs))
+	newStatic := make(map[int32]processStatic, len(procs))
+	cpuSampled := make(map[int]bool, len(procs))
 
 	cores := logicalCores
 	if cores <= 0 {
@@ -37,15 +54,26 @@ func (m *ResourceMonitor) sampleTopProcesses(now time.Time, logicalCores int, me
 		cores = 1
 	}
 
-	var topCPU *ProcessSample
-	var topMem *ProcessSample
-
+	out := make([]ProcessInfo, 0, len(procs))
 	for _, p := range procs {
 		if p == nil {
 			continue
 		}
 		pid := p.Pid
 		name, _ := p.NameWithContext(ctx)
+		info := ProcessInfo{PID: int(pid), Name: name}
+
+		createTime, _ := p.CreateTimeWithContext(ctx)
+		static, ok := m.processStatic[pid]
+		if !ok || static.createTime != createTime {
+			static = processStatic{createTime: createTime}
+			static.user, _ = p.UsernameWithContext(ctx)
+			static.cmdline, _ = p.CmdlineWithContext(ctx)
+		}
+		newStatic[pid] = static
+		info.User = static.user
+		info.Cmdline = static.cmdline
+		info.StartedAt = static.createTime
 
 		if times, err := p.TimesWithContext(ctx); err == nil {
 			totalCPU := cpuTimesTotalPtr(times)
@@ -63,40 +91,185 @@ func (m *ResourceMonitor) sampleTopProcesses(now time.Time, logicalCores int, me
 					if cpuPct > 100 {
 						cpuPct = math.Min(cpuPct, 100)
 					}
-					if topCPU == nil || cpuPct > topCPU.CPUPercent {
-						topCPU = &ProcessSample{
-							PID:        int(pid),
-							Name:       name,
-							CPUPercent: cpuPct,
-						}
-					}
+					info.CPUPercent = cpuPct
+					cpuSampled[int(pid)] = true
 				}
 			}
 		}
 
 		if memInfo, err := p.MemoryInfoWithContext(ctx); err == nil && memInfo != nil {
-			memBytes := memInfo.RSS
-			memPct := 0.0
+			info.MemoryBytes = memInfo.RSS
 			if memTotal > 0 {
-				memPct = float64(memBytes) / float64(memTotal) * 100
-			}
-			if topMem == nil || memBytes > topMem.MemoryBytes {
-				topMem = &ProcessSample{
-					PID:           int(pid),
-					Name:          name,
-					MemoryBytes:   memBytes,
-					MemoryPercent: memPct,
-				}
-			}
-			if topCPU != nil && topCPU.PID == int(pid) && topCPU.Name == name {
-				topCPU.MemoryBytes = memBytes
-				topCPU.MemoryPercent = memPct
+				info.MemoryPercent = float64(memInfo.RSS) / float64(memTotal) * 100
 			}
 		}
+
+		if threads, err := p.NumThreadsWithContext(ctx); err == nil {
+			info.Threads = int(threads)
+		}
+		if status, err := p.StatusWithContext(ctx); err == nil && len(status) > 0 {
+			info.State = status[0]
+		}
+
+		out = append(out, info)
 	}
 
 	m.prevProcessTimes = newPrev
+	m.processStatic = newStatic
 	m.lastProcessSample = now
+	m.processCPUSampled = cpuSampled
+
+	return out, nil
+}
+
+// topProcesses picks the busiest process by CPU and by resident memory.
+// Only processes in cpuSampled are candidates for CPU: the others report 0%
+// because they have no previous sample, so there is no top CPU process on
+// the first sample.
+func topProcesses(procs []ProcessInfo, cpuSampled map[int]bool) (*ProcessSample, *ProcessSample) {
+	var topCPU *ProcessSample
+	var topMem *ProcessSample
+	for _, p := range procs {
+		if cpuSampled[p.PID] && (topCPU == nil || p.CPUPercent > topCPU.CPUPercent) {
+			topCPU = &ProcessSample{
+				PID:           p.PID,
+				Name:          p.Name,
+				CPUPercent:    p.CPUPercent,
+				MemoryBytes:   p.MemoryBytes,
+				MemoryPercent: p.MemoryPercent,
+			}
+		}
+		if topMem == nil || p.MemoryBytes > topMem.MemoryBytes {
+			topMem = &ProcessSample{
+				PID:           p.PID,
+				Name:          p.Name,
+				MemoryBytes:   p.MemoryBytes,
+				MemoryPercent: p.MemoryPercent,
+			}
+		}
+	}
+	return topCPU, topMem
+}
+
+// appendProcessHistoryLocked records CPU and RSS for the top N processes by
+// CPU and forgets PIDs that haven't been in the top N for a while.
+func (m *ResourceMonitor) appendProcessHistoryLocked(now time.Time, procs []ProcessInfo) {
+	if m.processHistory == nil {
+		m.processHistory = make(map[int][]ProcessHistoryPoint)
+	}
+
+	top := append([]ProcessInfo(nil), procs...)
+	sort.Slice(top, func(i, j int) bool { return top[i].CPUPercent > top[j].CPUPercent })
+	if len(top) > processHistoryTopN {
+		top = top[:processHistoryTopN]
+	}
+
+	ts := now.UnixMilli()
+	for _, p := range top {
+		m.processHistory[p.PID] = append(m.processHistory[p.PID], ProcessHistoryPoint{
+			Time:        ts,
+			CPUPercent:  p.CPUPercent,
+			MemoryBytes: p.MemoryBytes,
+		})
+	}
+
+	cutoff := ts - int64(processHistoryMaxAge/time.Millisecond)
+	for pid, points := range m.processHistory {
+		trim := 0
+		for trim < len(points) && points[trim].Time < cutoff {
+			trim++
+		}
+		if trim == len(points) {
+			delete(m.processHistory, pid)
+			continue
+		}
+		if trim > 0 {
+			m.processHistory[pid] = append([]ProcessHistoryPoint(nil), points[trim:]...)
+		}
+	}
+}
+
+type ProcessQuery struct {
+	Sort   string
+	Desc   bool
+	Limit  int
+	Filter string
+	User   string
+	State  string
+}
+
+// Processes returns the last sampled process list filtered, sorted and
+// limited according to q.
+func (m *ResourceMonitor) Processes(q ProcessQuery, includeHistory bool) ProcessList {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	filter := strings.ToLower(strings.TrimSpace(q.Filter))
+	out := ProcessList{
+		UpdatedAt: m.snapshot.UpdatedAt,
+		Total:     len(m.processes),
+		Processes: make([]ProcessInfo, 0, len(m.processes)),
+	}
+	for _, p := range m.processes {
+		if q.User != "" && !strings.EqualFold(p.User, q.User) {
+			continue
+		}
+		if q.State != "" && !strings.EqualFold(p.State, q.State) {
+			continue
+		}
+		if filter != "" &&
+			!strings.Contains(strings.ToLower(p.Name), filter) &&
+			!strings.Contains(strings.ToLower(p.Cmdline), filter) &&
+			!strings.Contains(strings.ToLower(p.User), filter) {
+			continue
+		}
+		out.Processes = append(out.Processes, p)
+	}
+	out.Matched = len(out.Processes)
+
+	less := processLess(q.Sort)
+	sort.SliceStable(out.Processes, func(i, j int) bool {
+		if q.Desc {
+			return less(out.Processes[j], out.Processes[i])
+		}
+		return less(out.Processes[i], out.Processes[j])
+	})
+	if q.Limit > 0 && len(out.Processes) > q.Limit {
+		out.Processes = out.Processes[:q.Limit]
+	}
+
+	if includeHistory {
+		for _, p := range out.Processes {
+			points, ok := m.processHistory[p.PID]
+			if !ok {
+				continue
+			}
+			if out.History == nil {
+				out.History = make(map[int][]ProcessHistoryPoint)
+			}
+			out.History[p.PID] = append([]ProcessHistoryPoint(nil), points...)
+		}
+	}
+	return out
+}
 
-	return topCPU, topMem, nil
+func processLess(key string) func(a, b ProcessInfo) bool {
+	switch key {
+	case "pid":
+		return func(a, b ProcessInfo) bool { return a.PID < b.PID }
+	case "name":
+		return func(a, b ProcessInfo) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+	case "user":
+		return func(a, b ProcessInfo) bool { return a.User < b.User }
+	case "mem", "memory", "rss":
+		return func(a, b ProcessInfo) bool { return a.MemoryBytes < b.MemoryBytes }
+	case "threads":
+		return func(a, b ProcessInfo) bool { return a.Threads < b.Threads }
+	case "start", "started":
+		return func(a, b ProcessInfo) bool { return a.StartedAt < b.StartedAt }
+	case "state":
+		return func(a, b ProcessInfo) bool { return a.State < b.State }
+	default:
+		return func(a, b ProcessInfo) bool { return a.CPUPercent < b.CPUPercent }
+	}
 }
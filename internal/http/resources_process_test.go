@@ -0,0 +1,42 @@
+package http
+
+import "testing"
+
+func TestTopProcesses(t *testing.T) {
+	procs := []ProcessInfo{
+		{PID: 1, Name: "init", CPUPercent: 0, MemoryBytes: 12 << 20},
+		{PID: 200, Name: "postgres", CPUPercent: 3.5, MemoryBytes: 900 << 20},
+		{PID: 300, Name: "nginx", CPUPercent: 12, MemoryBytes: 40 << 20},
+		// Started since the last sample: 0% only because there is no
+		// previous reading.
+		{PID: 400, Name: "backup", CPUPercent: 0, MemoryBytes: 2 << 30},
+	}
+
+	topCPU, topMem := topProcesses(procs, map[int]bool{1: true, 200: true, 300: true})
+	if topCPU == nil || topCPU.PID != 300 || topCPU.CPUPercent != 12 {
+		t.Errorf("topCPU = %+v, want nginx", topCPU)
+	}
+	if topMem == nil || topMem.PID != 400 {
+		t.Errorf("topMem = %+v, want backup", topMem)
+	}
+
+	// Idle, but measured: still a top CPU process.
+	topCPU, _ = topProcesses(procs[:1], map[int]bool{1: true})
+	if topCPU == nil || topCPU.PID != 1 {
+		t.Errorf("topCPU = %+v, want init", topCPU)
+	}
+}
+
+func TestTopProcessesFirstSample(t *testing.T) {
+	procs := []ProcessInfo{
+		{PID: 1, Name: "init", MemoryBytes: 12 << 20},
+		{PID: 200, Name: "postgres", MemoryBytes: 900 << 20},
+	}
+	topCPU, topMem := topProcesses(procs, nil)
+	if topCPU != nil {
+		t.Errorf("topCPU = %+v before any process has a CPU reading", topCPU)
+	}
+	if topMem == nil || topMem.PID != 200 {
+		t.Errorf("topMem = %+v, want postgres", topMem)
+	}
+}
@@ -0,0 +1,288 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+const sysfsRoot = "/sys"
+
+type hwmonInput struct {
+	kind  string
+	unit  string
+	scale float64
+}
+
+// hwmonInputs maps sysfs hwmon file prefixes to what they measure and the
+// divisor from their raw unit (millidegrees, millivolts, microwatts).
+var hwmonInputs = map[string]hwmonInput{
+	"temp":  {kind: "temperature", unit: "C", scale: 1000},
+	"fan":   {kind: "fan", unit: "RPM", scale: 1},
+	"in":    {kind: "voltage", unit: "V", scale: 1000},
+	"power": {kind: "power", unit: "W", scale: 1_000_000},
+	"curr":  {kind: "current", unit: "A", scale: 1000},
+}
+
+func sampleSensors() ([]SensorReading, error) {
+	if runtime.GOOS == "linux" {
+		return readLinuxSensors(sysfsRoot)
+	}
+
+	temps, err := host.SensorsTemperatures()
+	if err != nil && len(temps) == 0 {
+		if isTemperatureUnavailable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	out := make([]SensorReading, 0, len(temps))
+	for _, t := range temps {
+		if t.Temperature <= 0 || !isFiniteFloat(t.Temperature) {
+			continue
+		}
+		r := SensorReading{
+			ID:    "sensor/" + t.SensorKey,
+			Chip:  "sensor",
+			Label: t.SensorKey,
+			Kind:  "temperature",
+			Unit:  "C",
+			Value: t.Temperature,
+		}
+		if t.High > 0 {
+			v := t.High
+			r.High = &v
+		}
+		if t.Critical > 0 {
+			v := t.Critical
+			r.Critical = &v
+		}
+		out = append(out, r)
+	}
+	return uniqueSensorLabels(out), nil
+}
+
+// readLinuxSensors reads every hwmon input and thermal zone below root
+// (normally /sys). IDs are built from the chip name and its parent device
+// rather than the hwmonN index, which can change between boots.
+func readLinuxSensors(root string) ([]SensorReading, error) {
+	var out []SensorReading
+	var errs []string
+
+	hwmonDir := filepath.Join(root, "class", "hwmon")
+	entries, err := os.ReadDir(hwmonDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		errs = append(errs, err.Error())
+	}
+	for _, c := range hwmonChips(root, entries) {
+		readings, err := readHwmonDir(c.dir, c.name, c.id)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", filepath.Base(c.dir), err))
+			continue
+		}
+		out = append(out, readings...)
+	}
+
+	thermalDir := filepath.Join(root, "class", "thermal")
+	zones, err := os.ReadDir(thermalDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		errs = append(errs, err.Error())
+	}
+	seenZones := make(map[string]int)
+	for _, z := range zones {
+		if !strings.HasPrefix(z.Name(), "thermal_zone") {
+			continue
+		}
+		dir := filepath.Join(thermalDir, z.Name())
+		milli, err := readIntFromFile(filepath.Join(dir, "temp"))
+		if err != nil || milli <= 0 {
+			continue
+		}
+		zoneType := readTrimmedFile(filepath.Join(dir, "type"))
+		if zoneType == "" {
+			zoneType = z.Name()
+		}
+		seenZones[zoneType]++
+		id := "thermal/" + zoneType
+		if n := seenZones[zoneType]; n > 1 {
+			id = fmt.Sprintf("%s#%d", id, n)
+		}
+		out = append(out, SensorReading{
+			ID:    id,
+			Chip:  "thermal",
+			Label: zoneType,
+			Kind:  "temperature",
+			Unit:  "C",
+			Value: float64(milli) / 1000,
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	out = uniqueSensorLabels(out)
+	if len(out) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return out, nil
+}
+
+type hwmonChip struct {
+	dir  string
+	name string
+	id   string
+	// device is the resolved device link, "" for virtual chips.
+	device string
+	// resolved is the resolved hwmon directory, used to order chips that
+	// can't be told apart otherwise.
+	resolved string
+}
+
+// hwmonChips names each chip "name@device", with the last element of its
+// device path. Chips that share both (several hwmon nodes of one device,
+// or identical devices on different buses) get the device path below
+// /sys/devices instead, and only chips without a device fall back to a
+// "#n" suffix.
+func hwmonChips(root string, entries []os.DirEntry) []hwmonChip {
+	hwmonDir := filepath.Join(root, "class", "hwmon")
+	devicesDir := filepath.Join(root, "devices")
+	if resolved, err := filepath.EvalSymlinks(devicesDir); err == nil {
+		devicesDir = resolved
+	}
+
+	chips := make([]hwmonChip, 0, len(entries))
+	for _, e := range entries {
+		c := hwmonChip{dir: filepath.Join(hwmonDir, e.Name())}
+		c.name = readTrimmedFile(filepath.Join(c.dir, "name"))
+		if c.name == "" {
+			c.name = e.Name()
+		}
+		c.id = c.name
+		if dev, err := filepath.EvalSymlinks(filepath.Join(c.dir, "device")); err == nil {
+			c.device = dev
+			c.id = c.name + "@" + filepath.Base(dev)
+		}
+		c.resolved, _ = filepath.EvalSymlinks(c.dir)
+		chips = append(chips, c)
+	}
+
+	count := make(map[string]int, len(chips))
+	for _, c := range chips {
+		count[c.id]++
+	}
+	for i, c := range chips {
+		if count[c.id] > 1 && c.device != "" {
+			dev := c.device
+			if rel, err := filepath.Rel(devicesDir, dev); err == nil && !strings.HasPrefix(rel, "..") {
+				dev = rel
+			}
+			chips[i].id = c.name + "@" + dev
+		}
+	}
+
+	sort.SliceStable(chips, func(i, j int) bool { return chips[i].resolved < chips[j].resolved })
+	seen := make(map[string]int, len(chips))
+	for i, c := range chips {
+		seen[c.id]++
+		if n := seen[c.id]; n > 1 {
+			chips[i].id = fmt.Sprintf("%s#%d", c.id, n)
+		}
+	}
+	return chips
+}
+
+func readHwmonDir(dir, chip, chipID string) ([]SensorReading, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []SensorReading
+	for _, f := range files {
+		name := f.Name()
+		if !strings.HasSuffix(name, "_input") {
+			continue
+		}
+		channel := strings.TrimSuffix(name, "_input")
+		prefix := strings.TrimRight(channel, "0123456789")
+		in, ok := hwmonInputs[prefix]
+		if !ok || prefix == channel {
+			continue
+		}
+
+		raw, err := readIntFromFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		value := float64(raw) / in.scale
+		if in.kind == "temperature" && value <= 0 {
+			continue
+		}
+
+		label := readTrimmedFile(filepath.Join(dir, channel+"_label"))
+		if label == "" {
+			label = channel
+		}
+		r := SensorReading{
+			ID:    chipID + "/" + channel,
+			Chip:  chip,
+			Label: chip + " " + label,
+			Kind:  in.kind,
+			Unit:  in.unit,
+			Value: value,
+		}
+		if v, err := readIntFromFile(filepath.Join(dir, channel+"_max")); err == nil && v > 0 {
+			high := float64(v) / in.scale
+			r.High = &high
+		}
+		if v, err := readIntFromFile(filepath.Join(dir, channel+"_crit")); err == nil && v > 0 {
+			crit := float64(v) / in.scale
+			r.Critical = &crit
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// uniqueSensorLabels appends the sensor ID to labels shared by several
+// readings, e.g. two identical NVMe drives both labelled "nvme Composite".
+func uniqueSensorLabels(readings []SensorReading) []SensorReading {
+	counts := make(map[string]int, len(readings))
+	for _, r := range readings {
+		counts[r.Label]++
+	}
+	for i := range readings {
+		if counts[readings[i].Label] > 1 {
+			readings[i].Label = readings[i].Label + " (" + readings[i].ID + ")"
+		}
+	}
+	return readings
+}
+
+func readTrimmedFile(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(b), "\x00"))
+}
+
+// sensorHistoryValues picks the readings worth keeping in history: the
+// graph can plot temperatures and fan speeds.
+func sensorHistoryValues(readings []SensorReading) map[string]float64 {
+	var out map[string]float64
+	for _, r := range readings {
+		if r.Kind != "temperature" && r.Kind != "fan" {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]float64)
+		}
+		out[r.ID] = r.Value
+	}
+	return out
+}
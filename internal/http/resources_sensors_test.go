@@ -0,0 +1,124 @@
+package http
+
+import (
+	"slices"
+	"testing"
+)
+
+var testHwmonChips = []fakeHwmonChip{
+	{device: "pci0000:00/0000:00:18.3", files: map[string]string{
+		"name":        "k10temp",
+		"temp1_input": "52125",
+		"temp1_label": "Tctl",
+		"temp1_crit":  "95000",
+	}},
+	{device: "pci0000:00/0000:00:01.1/0000:02:00.0", files: map[string]string{
+		"name":        "nvme",
+		"temp1_input": "38850",
+		"temp1_label": "Composite",
+		"temp1_max":   "81850",
+	}},
+	{device: "pci0000:40/0000:40:01.1/0000:02:00.0", files: map[string]string{
+		"name":        "nvme",
+		"temp1_input": "40850",
+		"temp1_label": "Composite",
+	}},
+	{device: "platform/nct6775.656", files: map[string]string{
+		"name":        "nct6798",
+		"fan1_input":  "1180",
+		"in0_input":   "1216",
+		"temp7_input": "0",
+	}},
+	{files: map[string]string{"name": "acpi_fan", "fan1_input": "900"}},
+	{files: map[string]string{"name": "acpi_fan", "fan1_input": "950"}},
+}
+
+func sensorsByID(t *testing.T, root string) map[string]SensorReading {
+	t.Helper()
+	readings, err := readLinuxSensors(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(map[string]SensorReading, len(readings))
+	for _, r := range readings {
+		if _, dup := out[r.ID]; dup {
+			t.Errorf("duplicate sensor ID %q", r.ID)
+		}
+		out[r.ID] = r
+	}
+	return out
+}
+
+func TestReadLinuxSensors(t *testing.T) {
+	got := sensorsByID(t, fakeSysfs(t, testHwmonChips))
+
+	ids := make([]string, 0, len(got))
+	for id := range got {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	want := []string{
+		"acpi_fan#2/fan1",
+		"acpi_fan/fan1",
+		"k10temp@0000:00:18.3/temp1",
+		"nct6798@nct6775.656/fan1",
+		"nct6798@nct6775.656/in0",
+		"nvme@pci0000:00/0000:00:01.1/0000:02:00.0/temp1",
+		"nvme@pci0000:40/0000:40:01.1/0000:02:00.0/temp1",
+		"thermal/x86_pkg_temp",
+	}
+	if !slices.Equal(ids, want) {
+		t.Fatalf("IDs = %q\nwant %q", ids, want)
+	}
+
+	tctl := got["k10temp@0000:00:18.3/temp1"]
+	if tctl.Label != "k10temp Tctl" || tctl.Kind != "temperature" || tctl.Value != 52.125 {
+		t.Errorf("k10temp = %+v", tctl)
+	}
+	if tctl.Critical == nil || *tctl.Critical != 95 {
+		t.Errorf("k10temp critical = %v, want 95", tctl.Critical)
+	}
+	nvme := got["nvme@pci0000:00/0000:00:01.1/0000:02:00.0/temp1"]
+	if nvme.High == nil || *nvme.High != 81.85 {
+		t.Errorf("nvme high = %v, want 81.85", nvme.High)
+	}
+	// Same label on both drives, so the ID is appended.
+	if nvme.Label != "nvme Composite (nvme@pci0000:00/0000:00:01.1/0000:02:00.0/temp1)" {
+		t.Errorf("nvme label = %q", nvme.Label)
+	}
+	if v := got["nct6798@nct6775.656/in0"]; v.Kind != "voltage" || v.Unit != "V" || v.Value != 1.216 {
+		t.Errorf("in0 = %+v", v)
+	}
+	if f := got["nct6798@nct6775.656/fan1"]; f.Kind != "fan" || f.Value != 1180 {
+		t.Errorf("fan1 = %+v", f)
+	}
+	if z := got["thermal/x86_pkg_temp"]; z.Value != 45 {
+		t.Errorf("thermal zone = %+v", z)
+	}
+}
+
+func TestReadLinuxSensorsStableIDs(t *testing.T) {
+	// Probe order (the hwmonN numbering) changes between boots; IDs of chips
+	// backed by a device must not.
+	reversed := slices.Clone(testHwmonChips)
+	slices.Reverse(reversed)
+
+	a := sensorsByID(t, fakeSysfs(t, testHwmonChips))
+	b := sensorsByID(t, fakeSysfs(t, reversed))
+	for id, r := range a {
+		if r.Chip == "acpi_fan" {
+			continue
+		}
+		other, ok := b[id]
+		if !ok || other.Value != r.Value {
+			t.Errorf("%s = %v in one order, %v (present: %v) in the other", id, r.Value, other.Value, ok)
+		}
+	}
+}
+
+func TestReadLinuxSensorsMissingTree(t *testing.T) {
+	readings, err := readLinuxSensors(t.TempDir())
+	if err != nil || readings != nil {
+		t.Errorf("got %v, %v; want nothing for a tree without hwmon", readings, err)
+	}
+}
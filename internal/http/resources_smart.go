@@ -0,0 +1,273 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	smartSampleTTL     = 5 * time.Minute
+	smartDeviceTimeout = 10 * time.Second
+)
+
+// smartctlOutput is the subset of `smartctl --json` output we report on.
+type smartctlOutput struct {
+	Smartctl struct {
+		ExitStatus int `json:"exit_status"`
+		Messages   []struct {
+			String   string `json:"string"`
+			Severity string `json:"severity"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+	Device struct {
+		Name     string `json:"name"`
+		InfoName string `json:"info_name"`
+		Type     string `json:"type"`
+		Protocol string `json:"protocol"`
+	} `json:"device"`
+	ModelName    string `json:"model_name"`
+	SerialNumber string `json:"serial_number"`
+	SmartStatus  *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature *struct {
+		Current float64 `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime *struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+	ATASmartAttributes *struct {
+		Table []struct {
+			ID    int    `json:"id"`
+			Name  string `json:"name"`
+			Value int    `json:"value"`
+			Raw   struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMeHealth *struct {
+		CriticalWarning int     `json:"critical_warning"`
+		Temperature     float64 `json:"temperature"`
+		PercentageUsed  int     `json:"percentage_used"`
+		PowerOnHours    int64   `json:"power_on_hours"`
+		MediaErrors     int64   `json:"media_errors"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+// errSmartStandby is returned for drives that --nocheck=standby skipped
+// rather than spin up.
+var errSmartStandby = errors.New("drive in standby")
+
+type smartctlScan struct {
+	Devices []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"devices"`
+}
+
+// sampleSMART refreshes SMART data in the background, since smartctl can
+// take seconds per drive, and returns the last completed result.
+func (m *ResourceMonitor) sampleSMART(now time.Time) (map[string]DiskHealth, error) {
+	m.smartMu.Lock()
+	defer m.smartMu.Unlock()
+
+	if !m.smartRunning && (m.smartUpdatedAt.IsZero() || now.Sub(m.smartUpdatedAt) >= smartSampleTTL) {
+		m.smartRunning = true
+		m.smartUpdatedAt = now
+		prev := m.smartCache
+		go func() {
+			health, err := collectSMART(prev)
+			m.smartMu.Lock()
+			defer m.smartMu.Unlock()
+			if health != nil || err == nil {
+				m.smartCache = health
+			}
+			m.smartErr = err
+			m.smartRunning = false
+		}()
+	}
+	return m.smartCache, m.smartErr
+}
+
+// collectSMART queries every drive smartctl finds. Drives in standby keep
+// their entry from prev.
+func collectSMART(prev map[string]DiskHealth) (map[string]DiskHealth, error) {
+	path, err := exec.LookPath("smartctl")
+	if err != nil {
+		return nil, fmt.Errorf("smartctl not found")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), smartDeviceTimeout)
+	out, err := exec.CommandContext(ctx, path, "--scan", "--json").Output()
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("smartctl --scan: %w", err)
+	}
+	var scan smartctlScan
+	if err := json.Unmarshal(out, &scan); err != nil {
+		return nil, fmt.Errorf("smartctl --scan: %w", err)
+	}
+
+	health := make(map[string]DiskHealth, len(scan.Devices))
+	var errs []string
+	for _, d := range scan.Devices {
+		args := []string{"--json", "--all", "--nocheck=standby"}
+		if d.Type != "" {
+			args = append(args, "--device="+d.Type)
+		}
+		args = append(args, d.Name)
+
+		ctx, cancel := context.WithTimeout(context.Background(), smartDeviceTimeout)
+		out, runErr := exec.CommandContext(ctx, path, args...).Output()
+		cancel()
+
+		// smartctl encodes drive problems in its exit status bits while still
+		// printing valid JSON, so only give up if the output doesn't parse.
+		h, err := parseSmartctlJSON(out)
+		if errors.Is(err, errSmartStandby) {
+			if p, ok := prev[smartDeviceKey(d.Name)]; ok {
+				health[smartDeviceKey(d.Name)] = p
+			}
+			continue
+		}
+		if err != nil {
+			if runErr != nil {
+				err = runErr
+			}
+			errs = append(errs, fmt.Sprintf("%s: %v", d.Name, err))
+			continue
+		}
+		health[smartDeviceKey(d.Name)] = h
+	}
+
+	if len(errs) > 0 {
+		return health, fmt.Errorf("smart: %s", strings.Join(errs, "; "))
+	}
+	return health, nil
+}
+
+// parseSmartctlJSON extracts health fields from `smartctl --json --all`.
+func parseSmartctlJSON(b []byte) (DiskHealth, error) {
+	var out smartctlOutput
+	if err := json.Unmarshal(b, &out); err != nil {
+		return DiskHealth{}, err
+	}
+
+	// Bits 0-1 mean the command line or device open failed, which is also
+	// how --nocheck reports a drive it left asleep.
+	if out.Smartctl.ExitStatus&0b11 != 0 {
+		for _, msg := range out.Smartctl.Messages {
+			if strings.HasPrefix(msg.String, "Device is in ") && strings.Contains(msg.String, " mode") {
+				return DiskHealth{}, errSmartStandby
+			}
+		}
+		for _, msg := range out.Smartctl.Messages {
+			if msg.Severity == "error" && msg.String != "" {
+				return DiskHealth{}, errors.New(msg.String)
+			}
+		}
+		return DiskHealth{}, fmt.Errorf("smartctl exit status %d", out.Smartctl.ExitStatus)
+	}
+
+	h := DiskHealth{
+		Device:   out.Device.Name,
+		Protocol: out.Device.Protocol,
+		Model:    strings.TrimSpace(out.ModelName),
+		Serial:   strings.TrimSpace(out.SerialNumber),
+		Status:   "unknown",
+	}
+	if out.SmartStatus != nil {
+		if out.SmartStatus.Passed {
+			h.Status = "passed"
+		} else {
+			h.Status = "failed"
+		}
+	}
+	if out.Temperature != nil && out.Temperature.Current > 0 {
+		v := out.Temperature.Current
+		h.TemperatureC = &v
+	}
+	if out.PowerOnTime != nil {
+		v := out.PowerOnTime.Hours
+		h.PowerOnHours = &v
+	}
+
+	if out.ATASmartAttributes != nil {
+		for _, a := range out.ATASmartAttributes.Table {
+			raw := a.Raw.Value
+			switch a.ID {
+			case 5:
+				h.ReallocatedSectors = &raw
+			case 197:
+				h.PendingSectors = &raw
+			case 177, 231, 233:
+				// Wear_Leveling_Count, SSD_Life_Left and Media_Wearout_Indicator
+				// all count down from 100 as the flash wears out.
+				if h.WearPercent == nil && a.Value > 0 && a.Value <= 100 {
+					used := float64(100 - a.Value)
+					h.WearPercent = &used
+				}
+			}
+		}
+	}
+
+	if n := out.NVMeHealth; n != nil {
+		if h.TemperatureC == nil && n.Temperature > 0 {
+			v := n.Temperature
+			h.TemperatureC = &v
+		}
+		if h.PowerOnHours == nil {
+			v := n.PowerOnHours
+			h.PowerOnHours = &v
+		}
+		used := float64(n.PercentageUsed)
+		h.WearPercent = &used
+		mediaErrors := n.MediaErrors
+		h.MediaErrors = &mediaErrors
+		if n.CriticalWarning != 0 && h.Status == "passed" {
+			h.Status = "warning"
+		}
+	}
+
+	if h.Status == "passed" && ((h.ReallocatedSectors != nil && *h.ReallocatedSectors > 0) || (h.PendingSectors != nil && *h.PendingSectors > 0)) {
+		h.Status = "warning"
+	}
+	return h, nil
+}
+
+// smartDeviceKey normalizes smartctl device names to the form used by
+// DiskStats.PhysicalDevice ("/dev/sda" -> "sda").
+func smartDeviceKey(name string) string {
+	return filepath.Base(strings.TrimSpace(name))
+}
+
+func attachDiskHealth(disks []DiskStats, health map[string]DiskHealth) []DiskStats {
+	if len(health) == 0 {
+		return disks
+	}
+	out := append([]DiskStats(nil), disks...)
+	for i := range out {
+		key := out[i].PhysicalDevice
+		if key == "" {
+			continue
+		}
+		h, ok := health[key]
+		if !ok && strings.HasPrefix(key, "nvme") {
+			// NVMe namespaces (nvme0n1) are scanned as the controller (nvme0).
+			if idx := strings.LastIndexByte(key, 'n'); idx > len("nvme") {
+				h, ok = health[key[:idx]]
+			}
+		}
+		if ok {
+			out[i].Health = &h
+		}
+	}
+	return out
+}
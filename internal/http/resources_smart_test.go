@@ -0,0 +1,146 @@
+package http
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSmartctlJSON(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    map[string]any
+	}{
+		{"ata.json", map[string]any{
+			"device":      "/dev/sda",
+			"protocol":    "ATA",
+			"model":       "Samsung SSD 870 EVO 1TB",
+			"status":      "passed",
+			"temperature": 34.0,
+			"powerOn":     int64(15233),
+			"reallocated": int64(0),
+			"pending":     int64(0),
+			"wear":        3.0,
+			"mediaErrors": nil,
+		}},
+		{"nvme.json", map[string]any{
+			"device":      "/dev/nvme0",
+			"protocol":    "NVMe",
+			"model":       "WD_BLACK SN850X 2000GB",
+			"status":      "passed",
+			"temperature": 41.0,
+			"powerOn":     int64(4821),
+			"reallocated": nil,
+			"pending":     nil,
+			"wear":        2.0,
+			"mediaErrors": int64(0),
+		}},
+		{"failing.json", map[string]any{
+			"device":      "/dev/sdb",
+			"protocol":    "ATA",
+			"model":       "WDC WD20EZRZ-00Z5HB0",
+			"status":      "failed",
+			"temperature": 38.0,
+			"powerOn":     int64(38211),
+			"reallocated": int64(1672),
+			"pending":     int64(24),
+			"wear":        nil,
+			"mediaErrors": nil,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			h, err := parseSmartctlJSON(readFixture(t, "smartctl", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]any{
+				"device":      h.Device,
+				"protocol":    h.Protocol,
+				"model":       h.Model,
+				"status":      h.Status,
+				"temperature": ptrValue(h.TemperatureC),
+				"powerOn":     ptrValue(h.PowerOnHours),
+				"reallocated": ptrValue(h.ReallocatedSectors),
+				"pending":     ptrValue(h.PendingSectors),
+				"wear":        ptrValue(h.WearPercent),
+				"mediaErrors": ptrValue(h.MediaErrors),
+			}
+			for k, want := range tt.want {
+				if got[k] != want {
+					t.Errorf("%s = %v (%T), want %v (%T)", k, got[k], got[k], want, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSmartctlJSONOpenFailed(t *testing.T) {
+	_, err := parseSmartctlJSON(readFixture(t, "smartctl", "open-failed.json"))
+	if err == nil || err.Error() != "Smartctl open device: /dev/sdc failed: Permission denied" {
+		t.Errorf("err = %v, want the smartctl error message", err)
+	}
+}
+
+func TestParseSmartctlJSONWarning(t *testing.T) {
+	// Passing drives with pending sectors or an NVMe critical warning are
+	// reported as "warning".
+	for name, body := range map[string]string{
+		"pending": `{"smartctl": {"exit_status": 0}, "smart_status": {"passed": true},
+			"ata_smart_attributes": {"table": [{"id": 197, "value": 100, "raw": {"value": 3}}]}}`,
+		"nvme critical": `{"smartctl": {"exit_status": 0}, "smart_status": {"passed": true},
+			"nvme_smart_health_information_log": {"critical_warning": 4}}`,
+	} {
+		h, err := parseSmartctlJSON([]byte(body))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if h.Status != "warning" {
+			t.Errorf("%s: status = %q, want warning", name, h.Status)
+		}
+	}
+}
+
+func TestParseSmartctlJSONStandby(t *testing.T) {
+	_, err := parseSmartctlJSON(readFixture(t, "smartctl", "standby.json"))
+	if !errors.Is(err, errSmartStandby) {
+		t.Errorf("err = %v, want errSmartStandby", err)
+	}
+}
+
+func TestCollectSMARTKeepsStandbyDrives(t *testing.T) {
+	// A fake smartctl that finds sda awake and sdb (and sdc) asleep.
+	dir := t.TempDir()
+	fixtures, err := filepath.Abs(filepath.Join("testdata", "smartctl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := `#!/bin/sh
+for arg; do last=$arg; done
+case "$last" in
+--json) echo '{"devices": [{"name": "/dev/sda", "type": "sat"}, {"name": "/dev/sdb", "type": "sat"}, {"name": "/dev/sdc", "type": "sat"}]}' ;;
+/dev/sda) cat '` + fixtures + `/ata.json' ;;
+*) cat '` + fixtures + `/standby.json'; exit 2 ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "smartctl"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	prev := map[string]DiskHealth{"sdb": {Device: "/dev/sdb", Status: "passed"}}
+	health, err := collectSMART(prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health["sda"].Model != "Samsung SSD 870 EVO 1TB" {
+		t.Errorf("sda = %+v, want a fresh reading", health["sda"])
+	}
+	if health["sdb"] != prev["sdb"] {
+		t.Errorf("sdb = %+v, want the previous reading", health["sdb"])
+	}
+	if _, ok := health["sdc"]; ok {
+		t.Error("sdc was never read but has an entry")
+	}
+}
@@ -0,0 +1,182 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tomek7667/links/pkg/api"
+)
+
+const systemdSampleTTL = 5 * time.Second
+
+var systemdShowProperties = []string{
+	"Id",
+	"Description",
+	"LoadState",
+	"ActiveState",
+	"SubState",
+	"NRestarts",
+	"MainPID",
+	"StateChangeTimestamp",
+}
+
+// systemdUnits is the configured unit list plus every unit a saved link
+// points at, normalised and de-duplicated.
+func (m *ResourceMonitor) systemdUnits() []string {
+	names := append([]string(nil), m.opts.SystemdUnits...)
+	if m.linkedUnits != nil {
+		names = append(names, m.linkedUnits()...)
+	}
+	var out []string
+	for _, n := range names {
+		n = api.NormalizeUnit(n)
+		if n != "" && !slices.Contains(out, n) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func sampleSystemdUnits(units []string) ([]SystemdUnitStatus, error) {
+	if len(units) == 0 {
+		return nil, nil
+	}
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("systemd units are only supported on linux")
+	}
+	systemctl, err := exec.LookPath("systemctl")
+	if err != nil {
+		return nil, fmt.Errorf("systemctl not found")
+	}
+
+	args := []string{"show", "--no-pager", "--property=" + strings.Join(systemdShowProperties, ",")}
+	// "--" keeps a unit name from ever being read as an option (-H, -M).
+	args = append(args, "--")
+	args = append(args, units...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, systemctl, args...).Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("systemctl show: %w", ctx.Err())
+		}
+		msg := ""
+		if ee, ok := err.(*exec.ExitError); ok {
+			msg = strings.ReplaceAll(strings.TrimSpace(string(ee.Stderr)), "\n", "; ")
+		}
+		if msg == "" {
+			return nil, fmt.Errorf("systemctl show: %w", err)
+		}
+		return nil, fmt.Errorf("systemctl show: %v: %s", err, msg)
+	}
+
+	parsed := parseSystemctlShow(strings.NewReader(string(out)))
+	byName := make(map[string]SystemdUnitStatus, len(parsed))
+	for _, u := range parsed {
+		byName[u.Name] = u
+	}
+
+	// Keep the configured order; systemctl prints blocks in argument order
+	// but aliases resolve to their real Id, so fall back to position.
+	result := make([]SystemdUnitStatus, 0, len(units))
+	for i, name := range units {
+		u, ok := byName[name]
+		if !ok && i < len(parsed) {
+			u = parsed[i]
+		}
+		u.Name = name
+		if u.LoadState == "" {
+			u.LoadState = "unknown"
+		}
+		result = append(result, u)
+	}
+	return result, nil
+}
+
+// parseSystemctlShow parses `systemctl show` output: KEY=value lines with a
+// blank line between units.
+func parseSystemctlShow(r io.Reader) []SystemdUnitStatus {
+	var (
+		out  []SystemdUnitStatus
+		cur  SystemdUnitStatus
+		seen bool
+	)
+	flush := func() {
+		if seen {
+			out = append(out, cur)
+		}
+		cur = SystemdUnitStatus{}
+		seen = false
+	}
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			flush()
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		seen = true
+		switch key {
+		case "Id":
+			cur.Name = value
+		case "Description":
+			cur.Description = value
+		case "LoadState":
+			cur.LoadState = value
+		case "ActiveState":
+			cur.ActiveState = value
+		case "SubState":
+			cur.SubState = value
+		case "NRestarts":
+			if n, err := strconv.Atoi(value); err == nil {
+				cur.Restarts = &n
+			}
+		case "MainPID":
+			if pid, err := strconv.Atoi(value); err == nil && pid > 0 {
+				cur.MainPID = pid
+			}
+		case "StateChangeTimestamp":
+			if t, ok := parseSystemdTimestamp(value); ok {
+				cur.Since = t.UnixMilli()
+			}
+		}
+	}
+	flush()
+	return out
+}
+
+// parseSystemdTimestamp handles the default "Sat 2026-10-17 09:12:01 CEST"
+// format as well as "@1760685121" from --timestamp=unix.
+func parseSystemdTimestamp(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "n/a" {
+		return time.Time{}, false
+	}
+	if strings.HasPrefix(value, "@") {
+		sec, err := strconv.ParseInt(value[1:], 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(sec, 0), true
+	}
+	for _, layout := range []string{"Mon 2006-01-02 15:04:05 MST", "Mon 2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const zfsSampleTTL = 30 * time.Second
+
+type zpoolInfo struct {
+	Health     string
+	SizeBytes  uint64
+	AllocBytes uint64
+}
+
+type zfsDatasetInfo struct {
+	UsedBytes     uint64
+	AvailBytes    uint64
+	QuotaBytes    uint64
+	RefquotaBytes uint64
+}
+
+// sampleZFS refreshes pool and dataset properties for mounted datasets.
+// It is only called when a zfs mount is shown.
+func (m *ResourceMonitor) sampleZFS(now time.Time) (map[string]zpoolInfo, map[string]zfsDatasetInfo, error) {
+	if !m.zfsUpdatedAt.IsZero() && now.Sub(m.zfsUpdatedAt) < zfsSampleTTL {
+		return m.zpools, m.zfsDatasets, m.zfsErr
+	}
+	m.zfsUpdatedAt = now
+
+	var errs []string
+	if out, err := runZFSCommand("zpool", "list", "-H", "-p", "-o", "name,health,size,alloc"); err != nil {
+		errs = append(errs, err.Error())
+	} else {
+		m.zpools = parseZpoolList(strings.NewReader(out))
+	}
+	if out, err := runZFSCommand("zfs", "list", "-H", "-p", "-t", "filesystem", "-o", "name,used,avail,quota,refquota"); err != nil {
+		errs = append(errs, err.Error())
+	} else {
+		m.zfsDatasets = parseZfsList(strings.NewReader(out))
+	}
+
+	m.zfsErr = nil
+	if len(errs) > 0 {
+		m.zfsErr = fmt.Errorf("zfs: %s", strings.Join(errs, "; "))
+	}
+	return m.zpools, m.zfsDatasets, m.zfsErr
+}
+
+func runZFSCommand(name string, args ...string) (string, error) {
+	bin, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("%s not found", name)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, bin, args...).Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("%s: %w", name, ctx.Err())
+		}
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return string(out), nil
+}
+
+// parseZpoolList parses `zpool list -H -p -o name,health,size,alloc`.
+func parseZpoolList(r io.Reader) map[string]zpoolInfo {
+	out := make(map[string]zpoolInfo)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Split(strings.TrimSpace(sc.Text()), "\t")
+		if len(fields) < 4 || fields[0] == "" {
+			continue
+		}
+		size, _ := strconv.ParseUint(fields[2], 10, 64)
+		alloc, _ := strconv.ParseUint(fields[3], 10, 64)
+		out[fields[0]] = zpoolInfo{Health: fields[1], SizeBytes: size, AllocBytes: alloc}
+	}
+	return out
+}
+
+// parseZfsList parses `zfs list -H -p -o name,used,avail,quota,refquota`.
+// Unset quotas are "0" with -p (or "-" / "none" without it).
+func parseZfsList(r io.Reader) map[string]zfsDatasetInfo {
+	num := func(s string) uint64 {
+		v, _ := strconv.ParseUint(s, 10, 64)
+		return v
+	}
+	out := make(map[string]zfsDatasetInfo)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Split(strings.TrimSpace(sc.Text()), "\t")
+		if len(fields) < 5 || fields[0] == "" {
+			continue
+		}
+		out[fields[0]] = zfsDatasetInfo{
+			UsedBytes:     num(fields[1]),
+			AvailBytes:    num(fields[2]),
+			QuotaBytes:    num(fields[3]),
+			RefquotaBytes: num(fields[4]),
+		}
+	}
+	return out
+}
+
+// attachZFS fills DiskStats.ZFS for zfs mounts, where Device is the dataset
+// name ("tank/data").
+func attachZFS(ds *DiskStats, pools map[string]zpoolInfo, datasets map[string]zfsDatasetInfo) {
+	pool, _, _ := strings.Cut(ds.Device, "/")
+	info := &ZFSInfo{Pool: pool, Dataset: ds.Device}
+	if p, ok := pools[pool]; ok {
+		info.PoolHealth = p.Health
+		info.PoolSizeBytes = p.SizeBytes
+		info.PoolAllocBytes = p.AllocBytes
+	}
+	if d, ok := datasets[ds.Device]; ok {
+		quota := d.QuotaBytes
+		if d.RefquotaBytes > 0 && (quota == 0 || d.RefquotaBytes < quota) {
+			quota = d.RefquotaBytes
+		}
+		if quota > 0 {
+			info.QuotaBytes = &quota
+		}
+	}
+	ds.ZFS = info
+}
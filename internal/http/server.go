@@ -4,90 +4,252 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/tomek7667/links/internal/domain"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 type Dber interface {
-	SaveLink(link domain.Link)
+	// SaveLink fails with domain.ErrLimitExceeded when a store limit is hit.
+	SaveLink(link domain.Link) error
 	GetLinks() []domain.Link
 	DeleteLink(url string)
 	Close()
 }
 
+type Options struct {
+	// Build is reported by /api/version and in the OpenAPI document.
+	Build BuildInfo
+
+	// IngestToken enables POST /api/ingest for agents pushing snapshots.
+	IngestToken string
+
+	// Listen replaces the default ":<port>" listener; see listeners.
+	Listen []string
+	// UnixSocketMode is applied to unix: sockets (default 0660).
+	UnixSocketMode os.FileMode
+
+	// BasePath serves everything below a prefix ("/links") for reverse
+	// proxies that don't strip it.
+	BasePath string
+	// TrustedProxies may set the client address via X-Forwarded-For.
+	TrustedProxies TrustedProxies
+
+	// AccessLog writes every request to a rotated file.
+	AccessLog AccessLogOptions
+	// LogIgnorePaths are request paths (globs, relative to BasePath) only
+	// logged at debug level; see DefaultLogIgnorePaths.
+	LogIgnorePaths []string
+
+	// Limits rate limit the API and cap request bodies.
+	Limits Limits
+
+	TLS       TLSOptions
+	Resources ResourceMonitorOptions
+}
+
 type Server struct {
 	port int
 	dber Dber
 	r    *chi.Mux
+	opts Options
+
+	// The API routes are registered on these so each group gets its own
+	// rate limit and body size cap; see Limits.
+	read   chi.Router
+	write  chi.Router
+	ingest chi.Router
+	// ingestLimit is keyed by agent name rather than address, so it is
+	// applied by the handler once the batch is decoded.
+	ingestLimit *rateLimiter
 
 	resources *ResourceMonitor
+	agents    *agentRegistry
+	accessLog *accessLogger
+	// acme is set when certificates come from an ACME CA.
+	acme *autocert.Manager
+
+	startedAt time.Time
+	// serving is true while the listeners accept connections; see readyz.
+	serving atomic.Bool
 }
 
-func New(port int, dber Dber) *Server {
+func New(port int, dber Dber, opts Options) *Server {
+	opts.BasePath = cleanBasePath(opts.BasePath)
 	s := &Server{
 		r:         chi.NewRouter(),
 		port:      port,
 		dber:      dber,
-		resources: NewResourceMonitor(),
+		opts:      opts,
+		resources: NewResourceMonitor(opts.Resources),
+		agents:    newAgentRegistry(),
+		accessLog: newAccessLogger(opts.AccessLog),
+		startedAt: time.Now(),
 	}
-	s.r.Use(newRequestLogger("/api/resources"))
+	s.resources.linkedUnits = s.linkedUnits
 	s.r.Use(middleware.RequestID)
-	s.r.Use(middleware.RealIP)
+	// Resolve the client address first so the request log shows it.
+	s.r.Use(newRealIP(opts.TrustedProxies))
+	s.r.Use(newRequestLogger(opts.BasePath, opts.LogIgnorePaths, s.accessLog))
 	s.r.Use(middleware.Recoverer)
 	s.r.Use(middleware.Timeout(60 * time.Second))
+	s.r.NotFound(notFound)
+	s.r.MethodNotAllowed(methodNotAllowed)
+
+	if opts.Limits.MaxBodyBytes <= 0 {
+		opts.Limits.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if opts.Limits.MaxIngestBodyBytes <= 0 {
+		opts.Limits.MaxIngestBodyBytes = DefaultMaxIngestBodyBytes
+	}
+	s.read = s.r.With(newRateLimit(newRateLimiter(opts.Limits.Read)))
+	s.write = s.r.With(newRateLimit(newRateLimiter(opts.Limits.Write)), newMaxBody(opts.Limits.MaxBodyBytes))
+	s.ingest = s.r.With(newMaxBody(opts.Limits.MaxIngestBodyBytes))
+	s.ingestLimit = newRateLimiter(opts.Limits.Ingest)
 	return s
 }
 
+// handler mounts the routes below BasePath when one is set.
+func (s *Server) handler() http.Handler {
+	base := s.opts.BasePath
+	if base == "" {
+		return s.r
+	}
+	root := chi.NewRouter()
+	root.NotFound(notFound)
+	root.Get(base, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, base+"/", http.StatusMovedPermanently)
+	})
+	root.Mount(base+"/", s.r)
+	// Container and orchestrator probes are usually configured without the
+	// prefix the reverse proxy adds.
+	root.Get("/healthz", s.healthz)
+	root.Get("/readyz", s.readyz)
+	return root
+}
+
+func (s *Server) linkedUnits() []string {
+	var units []string
+	for _, l := range s.dber.GetLinks() {
+		if l.Unit != "" {
+			units = append(units, l.Unit)
+		}
+	}
+	return units
+}
+
+// addRoutes registers every handler. buildOpenAPI documents all of them
+// except the UI at /.
+func (s *Server) addRoutes() {
+	s.AddIndexRoute()
+	s.AddIngestRoutes()
+	s.AddOpenAPIRoute()
+	s.AddHealthRoutes()
+}
+
 func (s *Server) Serve() error {
 	stopResources := make(chan struct{})
 	s.resources.Start(stopResources)
 	defer close(stopResources)
 	defer s.dber.Close()
 
-	s.AddIndexRoute()
+	s.addRoutes()
 
-	addr := fmt.Sprintf(":%d", s.port)
+	if s.accessLog != nil {
+		if err := s.accessLog.open(); err != nil {
+			return err
+		}
+		defer s.accessLog.Close()
+	}
+
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+
+	listeners, err := s.listeners()
+	if err != nil {
+		return err
+	}
+
+	errorLog := slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn)
 	srv := &http.Server{
-		Addr:    addr,
-		Handler: s.r,
+		Handler:     s.handler(),
+		TLSConfig:   tlsConfig,
+		ErrorLog:    errorLog,
+		ConnContext: withUnixPeer,
 	}
+	servers := []*http.Server{srv}
 
-	errCh := make(chan error, 1)
-	go func() {
-		fmt.Printf("listening on '%s'\n", addr)
-		err := srv.ListenAndServe()
+	errCh := make(chan error, len(listeners)+1)
+	serve := func(serve func() error) {
+		err := serve()
 		if errors.Is(err, http.ErrServerClosed) {
 			errCh <- nil
 			return
 		}
 		errCh <- err
-	}()
+	}
+
+	for _, l := range listeners {
+		if tlsConfig != nil && l.tls {
+			slog.Info("listening", "addr", l.name, "tls", true)
+			// Certificates come from TLSConfig.GetCertificate.
+			go serve(func() error { return srv.ServeTLS(l, "", "") })
+		} else {
+			slog.Info("listening", "addr", l.name)
+			go serve(func() error { return srv.Serve(l) })
+		}
+	}
+
+	if port := s.opts.TLS.RedirectPort; tlsConfig != nil && port != 0 {
+		handler := httpsRedirectHandler(httpsPort(listeners, s.port))
+		if s.acme != nil {
+			handler = s.acme.HTTPHandler(handler)
+		}
+		redirect := &http.Server{
+			Addr:              fmt.Sprintf(":%d", port),
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+			ErrorLog:          errorLog,
+		}
+		servers = append(servers, redirect)
+		slog.Info("redirecting http to https", "addr", redirect.Addr)
+		go serve(redirect.ListenAndServe)
+	}
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	defer signal.Stop(c)
 
-	select {
-	case sig := <-c:
-		fmt.Printf("received signal '%s', shutting down\n", sig)
+	s.serving.Store(true)
+	shutdown := func() {
+		// Report not ready while connections drain.
+		s.serving.Store(false)
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
-		_ = srv.Shutdown(ctx)
+		for _, srv := range servers {
+			_ = srv.Shutdown(ctx)
+		}
+	}
+
+	select {
+	case sig := <-c:
+		slog.Info("shutting down", "signal", sig.String())
+		shutdown()
 		return <-errCh
 	case err := <-errCh:
-		if err == nil {
-			return nil
-		}
-		if errors.Is(err, syscall.EACCES) && s.port < 1024 {
-			return fmt.Errorf("failed to listen on %s: %w (use --port 8080 or run with CAP_NET_BIND_SERVICE)", addr, err)
-		}
+		// One listener failing (or closing) takes the others down with it.
+		shutdown()
 		return err
 	}
 }
@@ -0,0 +1,295 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	certReloadCheckInterval = 5 * time.Second
+	selfSignedValidity      = 365 * 24 * time.Hour
+	// Regenerate a self-signed certificate this long before it expires.
+	selfSignedRenewBefore = 30 * 24 * time.Hour
+)
+
+type TLSOptions struct {
+	// CertFile and KeyFile are PEM files; they are re-read when changed.
+	CertFile string
+	KeyFile  string
+	// SelfSigned generates CertFile/KeyFile on first run (and when close to
+	// expiry) instead of requiring them to exist.
+	SelfSigned bool
+	// RedirectPort, when non-zero, serves plain HTTP on this port that
+	// redirects to HTTPS. With ACME it also answers HTTP-01 challenges.
+	RedirectPort int
+	// ACME obtains certificates automatically instead of reading files.
+	ACME ACMEOptions
+}
+
+func (o TLSOptions) enabled() bool {
+	return o.CertFile != "" || o.KeyFile != "" || o.SelfSigned || o.ACME.enabled()
+}
+
+// certReloader serves a key pair from disk and picks up replaced files
+// without a restart (e.g. after certbot renews them).
+type certReloader struct {
+	certFile string
+	keyFile  string
+	// selfSigned regenerates the pair when it gets close to expiry.
+	selfSigned bool
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	checkedAt time.Time
+	checking  bool
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	cert, certMod, keyMod, err := loadKeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &certReloader{certFile: certFile, keyFile: keyFile, cert: cert, certMod: certMod, keyMod: keyMod}, nil
+}
+
+func loadKeyPair(certFile, keyFile string) (cert *tls.Certificate, certMod, keyMod time.Time, err error) {
+	certInfo, err := os.Stat(certFile)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	keyInfo, err := os.Stat(keyFile)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, fmt.Errorf("failed to load key pair: %w", err)
+	}
+	return &pair, certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// GetCertificate serves the current pair and, every
+// certReloadCheckInterval, starts a check for a renewed or replaced one. The
+// check runs in the background so handshakes never wait for the disk or for
+// a new key to be generated.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.checking && time.Since(r.checkedAt) >= certReloadCheckInterval {
+		r.checking = true
+		r.checkedAt = time.Now()
+		go r.check()
+	}
+	return r.cert, nil
+}
+
+// check renews an expiring self-signed pair, so long-running servers don't
+// need a restart to get a new one, and swaps in the files if they changed.
+func (r *certReloader) check() {
+	r.mu.Lock()
+	cert, certMod, keyMod := r.cert, r.certMod, r.keyMod
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.checking = false
+		r.mu.Unlock()
+	}()
+
+	if r.selfSigned && cert.Leaf != nil && time.Until(cert.Leaf.NotAfter) <= selfSignedRenewBefore {
+		if err := ensureSelfSignedCert(r.certFile, r.keyFile); err != nil {
+			slog.Warn("failed to renew self-signed certificate", "file", r.certFile, "err", err)
+		}
+	}
+
+	certInfo, err1 := os.Stat(r.certFile)
+	keyInfo, err2 := os.Stat(r.keyFile)
+	if err1 != nil || err2 != nil || (certInfo.ModTime().Equal(certMod) && keyInfo.ModTime().Equal(keyMod)) {
+		return
+	}
+	// Keep serving the old pair if the new files are mid-write or don't
+	// match.
+	cert, certMod, keyMod, err := loadKeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		slog.Warn("keeping previous tls certificate", "file", r.certFile, "err", err)
+		return
+	}
+	r.mu.Lock()
+	r.cert, r.certMod, r.keyMod = cert, certMod, keyMod
+	r.mu.Unlock()
+	slog.Info("reloaded tls certificate", "file", r.certFile)
+}
+
+// tlsConfig returns nil when TLS isn't configured.
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	opts := s.opts.TLS
+	if !opts.enabled() {
+		return nil, nil
+	}
+
+	if opts.ACME.enabled() {
+		if opts.CertFile != "" || opts.KeyFile != "" || opts.SelfSigned {
+			return nil, fmt.Errorf("ACME can't be combined with certificate files or a self-signed certificate")
+		}
+		m, err := newACMEManager(opts.ACME)
+		if err != nil {
+			return nil, err
+		}
+		s.acme = m
+		cfg := m.TLSConfig()
+		cfg.MinVersion = tls.VersionTLS12
+		return cfg, nil
+	}
+
+	certFile, keyFile := opts.CertFile, opts.KeyFile
+	if opts.SelfSigned {
+		if certFile == "" {
+			certFile = "./linksserver.crt"
+		}
+		if keyFile == "" {
+			keyFile = "./linksserver.key"
+		}
+		if err := ensureSelfSignedCert(certFile, keyFile); err != nil {
+			return nil, fmt.Errorf("failed to create self-signed certificate: %w", err)
+		}
+	} else if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("both a TLS certificate and key are required")
+	}
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	reloader.selfSigned = opts.SelfSigned
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}, nil
+}
+
+// ensureSelfSignedCert writes a new self-signed pair unless a usable one
+// already exists at the given paths.
+func ensureSelfSignedCert(certFile, keyFile string) error {
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err == nil && time.Until(leaf.NotAfter) > selfSignedRenewBefore {
+			return nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		_, certErr := os.Stat(certFile)
+		_, keyErr := os.Stat(keyFile)
+		if certErr == nil && keyErr == nil {
+			return fmt.Errorf("existing %s / %s: %w", certFile, keyFile, err)
+		}
+	}
+
+	certPEM, keyPEM, err := generateSelfSignedCert(selfSignedHosts(), selfSignedValidity)
+	if err != nil {
+		return err
+	}
+	for _, f := range []string{certFile, keyFile} {
+		if dir := filepath.Dir(f); dir != "." {
+			if err := os.MkdirAll(dir, 0o700); err != nil {
+				return err
+			}
+		}
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(certFile, certPEM, 0o644); err != nil {
+		return err
+	}
+	slog.Info("generated self-signed certificate", "file", certFile)
+	return nil
+}
+
+func selfSignedHosts() []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if name, err := os.Hostname(); err == nil && name != "" && !slices.Contains(hosts, name) {
+		hosts = append(hosts, name)
+	}
+	if ip, err := preferredHostIP(); err == nil && ip != "" && !slices.Contains(hosts, ip) {
+		hosts = append(hosts, ip)
+	}
+	return hosts
+}
+
+func generateSelfSignedCert(hosts []string, validity time.Duration) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: hosts[0], Organization: []string{"linksserver self-signed"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// httpsRedirectHandler sends plain HTTP requests to the same host on the
+// HTTPS port.
+func httpsRedirectHandler(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.Trim(host, "[]")
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, fmt.Sprint(httpsPort))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
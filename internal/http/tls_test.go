@@ -0,0 +1,193 @@
+package http
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestCert(t *testing.T, certFile, keyFile string, validity time.Duration) {
+	t.Helper()
+	certPEM, keyPEM, err := generateSelfSignedCert([]string{"localhost"}, validity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, certPEM, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func leafNotAfter(t *testing.T, cert *tls.Certificate) time.Time {
+	t.Helper()
+	if cert == nil || cert.Leaf == nil {
+		t.Fatal("no parsed leaf certificate")
+	}
+	return cert.Leaf.NotAfter
+}
+
+func TestEnsureSelfSignedCert(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls", "linksserver.crt")
+	keyFile := filepath.Join(dir, "tls", "linksserver.key")
+
+	if err := ensureSelfSignedCert(certFile, keyFile); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.Leaf.VerifyHostname("localhost"); err != nil {
+		t.Error(err)
+	}
+	if info, err := os.Stat(keyFile); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0o600 {
+		t.Errorf("key file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// A pair that is still valid for long enough is kept.
+	if err := ensureSelfSignedCert(certFile, keyFile); err != nil {
+		t.Fatal(err)
+	}
+	again, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.Leaf.Equal(cert.Leaf) {
+		t.Error("valid certificate was replaced")
+	}
+
+	// One expiring within selfSignedRenewBefore is regenerated.
+	writeTestCert(t, certFile, keyFile, 10*24*time.Hour)
+	if err := ensureSelfSignedCert(certFile, keyFile); err != nil {
+		t.Fatal(err)
+	}
+	renewed, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Until(renewed.Leaf.NotAfter) < selfSignedValidity-time.Hour {
+		t.Errorf("NotAfter = %v, want a fresh certificate", renewed.Leaf.NotAfter)
+	}
+}
+
+func TestEnsureSelfSignedCertKeepsBrokenPair(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "linksserver.crt")
+	keyFile := filepath.Join(dir, "linksserver.key")
+	if err := os.WriteFile(certFile, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// Files someone put there are not overwritten.
+	if err := ensureSelfSignedCert(certFile, keyFile); err == nil {
+		t.Error("unreadable pair was replaced without an error")
+	}
+	if b, _ := os.ReadFile(certFile); string(b) != "not a certificate" {
+		t.Error("certificate file was overwritten")
+	}
+}
+
+// getCertificateAfterCheck calls GetCertificate with the check interval
+// passed, which must return at once, and then the pair the background check
+// left behind.
+func getCertificateAfterCheck(t *testing.T, r *certReloader) (before, after *tls.Certificate) {
+	t.Helper()
+	r.mu.Lock()
+	r.checkedAt = time.Time{}
+	r.mu.Unlock()
+	before, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		r.mu.Lock()
+		checking := r.checking
+		r.mu.Unlock()
+		if !checking {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("certificate check didn't finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	after, err = r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return before, after
+}
+
+func TestCertReloaderRenewsSelfSigned(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "linksserver.crt")
+	keyFile := filepath.Join(dir, "linksserver.key")
+	// Fresh at startup, but close to expiry by the time it is served again.
+	writeTestCert(t, certFile, keyFile, 20*24*time.Hour)
+
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.selfSigned = true
+	expiring := leafNotAfter(t, r.cert)
+
+	before, cert := getCertificateAfterCheck(t, r)
+	if !leafNotAfter(t, before).Equal(expiring) {
+		t.Error("the handshake waited for the renewal")
+	}
+	if got := leafNotAfter(t, cert); !got.After(expiring) || time.Until(got) < selfSignedValidity-time.Hour {
+		t.Errorf("NotAfter = %v, want a renewed certificate", got)
+	}
+
+	// Certificates from files the user manages are left alone.
+	writeTestCert(t, certFile, keyFile, 20*24*time.Hour)
+	r, err = newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiring = leafNotAfter(t, r.cert)
+	if _, cert := getCertificateAfterCheck(t, r); !leafNotAfter(t, cert).Equal(expiring) {
+		t.Error("user-provided certificate was regenerated")
+	}
+}
+
+func TestCertReloaderPicksUpReplacedFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeTestCert(t, certFile, keyFile, selfSignedValidity)
+
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := r.cert
+
+	writeTestCert(t, certFile, keyFile, 90*24*time.Hour)
+	later := time.Now().Add(time.Second)
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Within the check interval the cached pair is served.
+	r.checkedAt = time.Now()
+	if cert, _ := r.GetCertificate(nil); cert != old {
+		t.Error("reloaded before the check interval passed")
+	}
+	if _, cert := getCertificateAfterCheck(t, r); cert == old {
+		t.Error("replaced files were not picked up")
+	}
+}
@@ -3,20 +3,22 @@ package json
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
-	"time"
 )
 
 func (c *Client) autosave() {
+	c.m.Lock()
 	b, err := json.Marshal(c.db)
+	c.m.Unlock()
 	if err != nil {
 		panic(fmt.Errorf("failed to marshal the database: %w", err))
 	}
 	if err := os.WriteFile(c.Path, b, 0o644); err != nil {
-		fmt.Printf("state not saved to the db:\n---\n%s\n---\n", string(b))
+		slog.Error("state not saved to the db", "path", c.Path, "state", string(b))
 		panic(fmt.Errorf("failed to autosave the database: %w", err))
 	}
-	fmt.Printf("autosaved %s\n", time.Now().Format(time.RFC3339))
+	slog.Info("autosaved", "path", c.Path)
 }
 
 func (c *Client) Close() {
@@ -9,19 +9,21 @@ import (
 )
 
 type Client struct {
-	Path string
-	db   Db
-	m    sync.Mutex
+	Path   string
+	Limits Limits
+	db     Db
+	m      sync.Mutex
 }
 
 type Db struct {
 	Links []domain.Link `json:"links"`
 }
 
-func New() (*Client, error) {
+func New(limits Limits) (*Client, error) {
 	c := &Client{
-		Path: "./links.db.json",
-		m:    sync.Mutex{},
+		Path:   "./links.db.json",
+		Limits: limits,
+		m:      sync.Mutex{},
 	}
 	if !c.dbExists() {
 		err := c.writeDb()
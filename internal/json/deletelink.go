@@ -4,12 +4,14 @@ import (
 	"slices"
 
 	"github.com/tomek7667/links/internal/domain"
+	"github.com/tomek7667/links/pkg/api"
 )
 
 func (c *Client) DeleteLink(url string) {
+	key := api.URLKey(url)
 	c.m.Lock()
 	idx := slices.IndexFunc(c.db.Links, func(l domain.Link) bool {
-		return l.Url == url
+		return api.URLKey(l.Url) == key
 	})
 	if idx != -1 {
 		c.db.Links = append(c.db.Links[:idx], c.db.Links[idx+1:]...)
@@ -1,7 +1,15 @@
 package json
 
-import "github.com/tomek7667/links/internal/domain"
+import (
+	"slices"
 
+	"github.com/tomek7667/links/internal/domain"
+)
+
+// GetLinks returns a copy of the saved links; it is called from the
+// resource monitor as well as from request handlers.
 func (c *Client) GetLinks() []domain.Link {
-	return c.db.Links
+	c.m.Lock()
+	defer c.m.Unlock()
+	return slices.Clone(c.db.Links)
 }
@@ -0,0 +1,35 @@
+package json
+
+import (
+	"fmt"
+
+	"github.com/tomek7667/links/internal/domain"
+)
+
+// Limits bound what SaveLink accepts so a misbehaving client can't grow the
+// database without end. Zero disables a limit.
+type Limits struct {
+	MaxLinks int
+	// MaxFieldLength applies to the title, url and unit, in bytes.
+	MaxFieldLength int
+}
+
+// check is called with c.m held. replacing is true when link overwrites an
+// existing entry, which doesn't count against MaxLinks.
+func (c *Client) check(link domain.Link, replacing bool) error {
+	if max := c.Limits.MaxFieldLength; max > 0 {
+		for _, f := range []struct{ name, value string }{
+			{"title", link.Title},
+			{"url", link.Url},
+			{"unit", link.Unit},
+		} {
+			if len(f.value) > max {
+				return fmt.Errorf("%w: %s is longer than %d bytes", domain.ErrLimitExceeded, f.name, max)
+			}
+		}
+	}
+	if max := c.Limits.MaxLinks; max > 0 && !replacing && len(c.db.Links) >= max {
+		return fmt.Errorf("%w: at most %d links can be saved", domain.ErrLimitExceeded, max)
+	}
+	return nil
+}
@@ -4,18 +4,29 @@ import (
 	"slices"
 
 	"github.com/tomek7667/links/internal/domain"
+	"github.com/tomek7667/links/pkg/api"
 )
 
-func (c *Client) SaveLink(link domain.Link) {
+func (c *Client) SaveLink(link domain.Link) error {
+	link, err := api.NormalizeLink(link)
+	if err != nil {
+		return err
+	}
 	c.m.Lock()
+	defer c.m.Unlock()
+	// Links saved before normalisation may differ from link.Url only by
+	// case or a trailing slash; they are replaced too.
 	idx := slices.IndexFunc(c.db.Links, func(l domain.Link) bool {
-		return l.Url == link.Url
+		return api.URLKey(l.Url) == link.Url
 	})
+	if err := c.check(link, idx != -1); err != nil {
+		return err
+	}
 	if idx == -1 {
 		c.db.Links = append(c.db.Links, link)
 	} else {
 		c.db.Links[idx] = link
 	}
 	go c.autosave()
-	c.m.Unlock()
+	return nil
 }
@@ -0,0 +1,19 @@
+package api
+
+// FieldError describes one invalid field of a rejected request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ErrorResponse is the body of every error the server answers with.
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Fields lists the invalid fields for validation_failed.
+	Fields []FieldError `json:"fields,omitempty"`
+}
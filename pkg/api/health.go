@@ -0,0 +1,24 @@
+package api
+
+// BuildInfo describes the running binary, as read from debug.BuildInfo.
+type BuildInfo struct {
+	// Version is the printable version: the module version, or the VCS
+	// revision for development builds.
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	Modified  bool   `json:"modified"`
+	GoVersion string `json:"goVersion"`
+}
+
+type VersionInfo struct {
+	BuildInfo
+	StartedAt     int64 `json:"startedAt"`
+	UptimeSeconds int64 `json:"uptimeSeconds"`
+}
+
+// Readiness is the body of /readyz. Checks maps each dependency to "ok" or
+// the reason it isn't ready.
+type Readiness struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
@@ -0,0 +1,14 @@
+package api
+
+// IngestBatch is the payload an agent pushes to the central server.
+type IngestBatch struct {
+	Agent     string              `json:"agent"`
+	Snapshots []ResourcesSnapshot `json:"snapshots"`
+}
+
+type AgentStatus struct {
+	Name       string            `json:"name"`
+	LastSeenAt int64             `json:"lastSeenAt"`
+	Received   int               `json:"received"`
+	Snapshot   ResourcesSnapshot `json:"snapshot"`
+}
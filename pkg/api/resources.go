@@ -0,0 +1,423 @@
+// Package api holds the JSON types linksserver's HTTP API sends and accepts,
+// as described by /api/openapi.json, and the rules links are validated by.
+// It only depends on the standard library, so clients can use it without
+// pulling in the server.
+package api
+
+// Link is a saved link, as listed by /api/links and suggested for
+// containers.
+type Link struct {
+	Title string `json:"title"`
+	Url   string `json:"url"`
+	// Unit optionally names the systemd unit serving this link so its
+	// status can be shown next to it.
+	Unit string `json:"unit,omitempty"`
+}
+
+type ResourcesSnapshot struct {
+	HostIP     string              `json:"hostIp"`
+	UpdatedAt  int64               `json:"updatedAt"`
+	CPU        CPUStats            `json:"cpu"`
+	Memory     MemoryStats         `json:"memory"`
+	Cgroup     *CgroupStats        `json:"cgroup,omitempty"`
+	Disks      []DiskStats         `json:"disks"`
+	GPUs       []GPUStats          `json:"gpus,omitempty"`
+	Network    NetworkStats        `json:"network"`
+	Sensors    []SensorReading     `json:"sensors,omitempty"`
+	Pi         *PiStats            `json:"pi,omitempty"`
+	Power      *PowerStats         `json:"power,omitempty"`
+	Containers []ContainerStats    `json:"containers,omitempty"`
+	Systemd    []SystemdUnitStatus `json:"systemd,omitempty"`
+	Processes  int                 `json:"processes"`
+	TopCPU     *ProcessSample      `json:"topCpu,omitempty"`
+	TopMemory  *ProcessSample      `json:"topMemory,omitempty"`
+	History    []HistoryPoint      `json:"history,omitempty"`
+	Errors     SnapshotError       `json:"errors"`
+}
+
+type SnapshotError struct {
+	CPU        string `json:"cpu"`
+	Memory     string `json:"memory"`
+	Disks      string `json:"disks"`
+	GPUs       string `json:"gpus"`
+	Network    string `json:"network"`
+	Sensors    string `json:"sensors"`
+	Pi         string `json:"pi"`
+	Containers string `json:"containers"`
+	Systemd    string `json:"systemd"`
+	Cgroup     string `json:"cgroup"`
+	Power      string `json:"power"`
+	HostIP     string `json:"hostIp"`
+}
+
+type CPUStats struct {
+	Percent             float64      `json:"percent"`
+	Model               string       `json:"model"`
+	PhysicalCores       int          `json:"physicalCores"`
+	LogicalCores        int          `json:"logicalCores"`
+	CurrentMHz          float64      `json:"currentMHz"`
+	MaxMHz              float64      `json:"maxMHz"`
+	CurrentPercentOfMax float64      `json:"currentPercentOfMax"`
+	TemperatureC        *float64     `json:"temperatureC,omitempty"`
+	PerformanceCores    int          `json:"performanceCores"`
+	EfficiencyCores     int          `json:"efficiencyCores"`
+	PerformanceThreads  int          `json:"performanceThreads"`
+	EfficiencyThreads   int          `json:"efficiencyThreads"`
+	Load                *LoadAverage `json:"load,omitempty"`
+	// PerCore is the busy percent of each logical CPU.
+	PerCore   []float64     `json:"perCore,omitempty"`
+	Breakdown *CPUBreakdown `json:"breakdown,omitempty"`
+	// Pressure and IOPressure are from /proc/pressure (Linux 4.20+).
+	Pressure   *PressureStat `json:"pressure,omitempty"`
+	IOPressure *PressureStat `json:"ioPressure,omitempty"`
+}
+
+type LoadAverage struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+}
+
+// CPUBreakdown splits CPU time between samples into percentages.
+type CPUBreakdown struct {
+	User   float64 `json:"user"`
+	Nice   float64 `json:"nice"`
+	System float64 `json:"system"`
+	IRQ    float64 `json:"irq"`
+	IOWait float64 `json:"iowait"`
+	Steal  float64 `json:"steal"`
+	Idle   float64 `json:"idle"`
+}
+
+type MemoryStats struct {
+	TotalBytes      uint64             `json:"totalBytes"`
+	UsedBytes       uint64             `json:"usedBytes"`
+	UsedPercent     float64            `json:"usedPercent"`
+	SwapTotalBytes  uint64             `json:"swapTotalBytes"`
+	SwapUsedBytes   uint64             `json:"swapUsedBytes"`
+	SwapUsedPercent float64            `json:"swapUsedPercent"`
+	Modules         []MemoryModuleInfo `json:"modules,omitempty"`
+	SwapDevices     []SwapDeviceStats  `json:"swapDevices,omitempty"`
+	Pressure        *PressureStat      `json:"pressure,omitempty"`
+}
+
+type MemoryModuleInfo struct {
+	Label     string `json:"label"`
+	Vendor    string `json:"vendor"`
+	SizeBytes uint64 `json:"sizeBytes"`
+}
+
+type SwapDeviceStats struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	SizeBytes uint64 `json:"sizeBytes"`
+	UsedBytes uint64 `json:"usedBytes"`
+}
+
+type DiskStats struct {
+	Mountpoint string `json:"mountpoint"`
+	// Name is the configured display name, if any.
+	Name           string       `json:"name,omitempty"`
+	Device         string       `json:"device"`
+	PhysicalDevice string       `json:"physicalDevice,omitempty"`
+	Filesystem     string       `json:"filesystem"`
+	DriveType      string       `json:"driveType"`
+	Model          string       `json:"model"`
+	TotalBytes     uint64       `json:"totalBytes"`
+	UsedBytes      uint64       `json:"usedBytes"`
+	UsedPercent    float64      `json:"usedPercent"`
+	IO             *DiskIOStats `json:"io,omitempty"`
+	Health         *DiskHealth  `json:"health,omitempty"`
+	ZFS            *ZFSInfo     `json:"zfs,omitempty"`
+}
+
+type ZFSInfo struct {
+	Pool           string `json:"pool"`
+	Dataset        string `json:"dataset"`
+	PoolHealth     string `json:"poolHealth,omitempty"`
+	PoolSizeBytes  uint64 `json:"poolSizeBytes,omitempty"`
+	PoolAllocBytes uint64 `json:"poolAllocBytes,omitempty"`
+	// QuotaBytes is the smaller of quota and refquota, nil when neither is set.
+	QuotaBytes *uint64 `json:"quotaBytes,omitempty"`
+}
+
+type DiskIOStats struct {
+	Device           string  `json:"device"`
+	ReadBytesPerSec  float64 `json:"readBytesPerSec"`
+	WriteBytesPerSec float64 `json:"writeBytesPerSec"`
+	ReadIOPS         float64 `json:"readIops"`
+	WriteIOPS        float64 `json:"writeIops"`
+	UtilPercent      float64 `json:"utilPercent"`
+	AwaitMs          float64 `json:"awaitMs"`
+	QueueDepth       float64 `json:"queueDepth"`
+}
+
+// DiskHealth is the SMART summary of a physical drive.
+type DiskHealth struct {
+	Device             string   `json:"device"`
+	Protocol           string   `json:"protocol,omitempty"`
+	Model              string   `json:"model,omitempty"`
+	Serial             string   `json:"serial,omitempty"`
+	Status             string   `json:"status"`
+	TemperatureC       *float64 `json:"temperatureC,omitempty"`
+	PowerOnHours       *int64   `json:"powerOnHours,omitempty"`
+	ReallocatedSectors *int64   `json:"reallocatedSectors,omitempty"`
+	PendingSectors     *int64   `json:"pendingSectors,omitempty"`
+	MediaErrors        *int64   `json:"mediaErrors,omitempty"`
+	WearPercent        *float64 `json:"wearPercent,omitempty"`
+}
+
+type GPUStats struct {
+	Index              int      `json:"index"`
+	Name               string   `json:"name"`
+	Vendor             string   `json:"vendor"`
+	Driver             string   `json:"driver"`
+	PCIAddress         string   `json:"pciAddress,omitempty"`
+	UtilizationPercent *float64 `json:"utilizationPercent,omitempty"`
+	MemoryTotalBytes   *uint64  `json:"memoryTotalBytes,omitempty"`
+	MemoryUsedBytes    *uint64  `json:"memoryUsedBytes,omitempty"`
+	TemperatureC       *float64 `json:"temperatureC,omitempty"`
+	PowerWatts         *float64 `json:"powerWatts,omitempty"`
+	ClockMHz           *float64 `json:"clockMHz,omitempty"`
+	MaxClockMHz        *float64 `json:"maxClockMHz,omitempty"`
+	PowerLimitWatts    *float64 `json:"powerLimitWatts,omitempty"`
+	MemoryClockMHz     *float64 `json:"memoryClockMHz,omitempty"`
+	FanPercent         *float64 `json:"fanPercent,omitempty"`
+	EncoderPercent     *float64 `json:"encoderPercent,omitempty"`
+	DecoderPercent     *float64 `json:"decoderPercent,omitempty"`
+	// ECC error counts since the driver loaded; nil without ECC memory.
+	ECCCorrected   *uint64      `json:"eccCorrected,omitempty"`
+	ECCUncorrected *uint64      `json:"eccUncorrected,omitempty"`
+	Processes      []GPUProcess `json:"processes,omitempty"`
+}
+
+type GPUProcess struct {
+	PID             int    `json:"pid"`
+	Name            string `json:"name"`
+	MemoryUsedBytes uint64 `json:"memoryUsedBytes"`
+}
+
+type NetworkStats struct {
+	// Totals exclude loopback interfaces.
+	RxBytesPerSec float64             `json:"rxBytesPerSec"`
+	TxBytesPerSec float64             `json:"txBytesPerSec"`
+	Interfaces    []NetInterfaceStats `json:"interfaces,omitempty"`
+	TCP           map[string]int      `json:"tcp,omitempty"`
+}
+
+type NetInterfaceStats struct {
+	Name            string   `json:"name"`
+	MAC             string   `json:"mac,omitempty"`
+	Addresses       []string `json:"addresses,omitempty"`
+	Up              bool     `json:"up"`
+	Loopback        bool     `json:"loopback"`
+	SpeedMbps       int      `json:"speedMbps,omitempty"`
+	RxBytesPerSec   float64  `json:"rxBytesPerSec"`
+	TxBytesPerSec   float64  `json:"txBytesPerSec"`
+	RxPacketsPerSec float64  `json:"rxPacketsPerSec"`
+	TxPacketsPerSec float64  `json:"txPacketsPerSec"`
+	ErrorsPerSec    float64  `json:"errorsPerSec"`
+	DropsPerSec     float64  `json:"dropsPerSec"`
+	RxBytes         uint64   `json:"rxBytes"`
+	TxBytes         uint64   `json:"txBytes"`
+	RxErrors        uint64   `json:"rxErrors"`
+	TxErrors        uint64   `json:"txErrors"`
+	RxDropped       uint64   `json:"rxDropped"`
+	TxDropped       uint64   `json:"txDropped"`
+}
+
+type SensorReading struct {
+	// ID is stable across reboots, e.g. "nvme@nvme0/temp1" or "thermal/cpu-thermal".
+	ID       string   `json:"id"`
+	Chip     string   `json:"chip"`
+	Label    string   `json:"label"`
+	Kind     string   `json:"kind"`
+	Unit     string   `json:"unit"`
+	Value    float64  `json:"value"`
+	High     *float64 `json:"high,omitempty"`
+	Critical *float64 `json:"critical,omitempty"`
+}
+
+// PiStats reports Raspberry Pi firmware health. The boolean fields without
+// the Occurred suffix describe the current state; the Occurred ones are
+// sticky since boot.
+type PiStats struct {
+	Model                 string   `json:"model"`
+	Source                string   `json:"source,omitempty"`
+	ThrottledRaw          string   `json:"throttledRaw,omitempty"`
+	Healthy               bool     `json:"healthy"`
+	UnderVoltage          bool     `json:"underVoltage"`
+	FreqCapped            bool     `json:"freqCapped"`
+	Throttled             bool     `json:"throttled"`
+	SoftTempLimit         bool     `json:"softTempLimit"`
+	UnderVoltageOccurred  bool     `json:"underVoltageOccurred"`
+	FreqCappedOccurred    bool     `json:"freqCappedOccurred"`
+	ThrottledOccurred     bool     `json:"throttledOccurred"`
+	SoftTempLimitOccurred bool     `json:"softTempLimitOccurred"`
+	CoreVolts             *float64 `json:"coreVolts,omitempty"`
+	ARMClockMHz           *float64 `json:"armClockMHz,omitempty"`
+	GPUClockMHz           *float64 `json:"gpuClockMHz,omitempty"`
+}
+
+type ContainerStats struct {
+	ID               string          `json:"id"`
+	Name             string          `json:"name"`
+	Image            string          `json:"image"`
+	State            string          `json:"state"`
+	Status           string          `json:"status"`
+	Health           string          `json:"health,omitempty"`
+	CreatedAt        int64           `json:"createdAt,omitempty"`
+	StartedAt        int64           `json:"startedAt,omitempty"`
+	RestartCount     int             `json:"restartCount"`
+	CPUPercent       *float64        `json:"cpuPercent,omitempty"`
+	MemoryBytes      *uint64         `json:"memoryBytes,omitempty"`
+	MemoryLimitBytes *uint64         `json:"memoryLimitBytes,omitempty"`
+	Ports            []ContainerPort `json:"ports,omitempty"`
+	SuggestedLinks   []Link          `json:"suggestedLinks,omitempty"`
+}
+
+type ContainerPort struct {
+	IP          string `json:"ip,omitempty"`
+	PrivatePort int    `json:"privatePort"`
+	PublicPort  int    `json:"publicPort,omitempty"`
+	Type        string `json:"type"`
+}
+
+type ProcessSample struct {
+	PID           int     `json:"pid"`
+	Name          string  `json:"name"`
+	CPUPercent    float64 `json:"cpuPercent,omitempty"`
+	MemoryBytes   uint64  `json:"memoryBytes,omitempty"`
+	MemoryPercent float64 `json:"memoryPercent,omitempty"`
+}
+
+type ProcessInfo struct {
+	PID           int     `json:"pid"`
+	Name          string  `json:"name"`
+	User          string  `json:"user"`
+	Cmdline       string  `json:"cmdline"`
+	CPUPercent    float64 `json:"cpuPercent"`
+	MemoryBytes   uint64  `json:"memoryBytes"`
+	MemoryPercent float64 `json:"memoryPercent"`
+	Threads       int     `json:"threads"`
+	StartedAt     int64   `json:"startedAt"`
+	State         string  `json:"state"`
+}
+
+type ProcessHistoryPoint struct {
+	Time        int64   `json:"time"`
+	CPUPercent  float64 `json:"cpuPercent"`
+	MemoryBytes uint64  `json:"memoryBytes"`
+}
+
+type ProcessList struct {
+	UpdatedAt int64                         `json:"updatedAt"`
+	Total     int                           `json:"total"`
+	Matched   int                           `json:"matched"`
+	Processes []ProcessInfo                 `json:"processes"`
+	History   map[int][]ProcessHistoryPoint `json:"history,omitempty"`
+}
+
+type HistoryPoint struct {
+	Time   int64    `json:"time"`
+	CPU    float64  `json:"cpu"`
+	Mem    float64  `json:"mem"`
+	NetRx  float64  `json:"netRx"`
+	NetTx  float64  `json:"netTx"`
+	Load1  *float64 `json:"load1,omitempty"`
+	IOWait float64  `json:"iowait"`
+	Steal  float64  `json:"steal"`
+	// Pressure holds PSI "some" avg10 for cpu, memory and io.
+	Pressure map[string]float64 `json:"pressure,omitempty"`
+	Disks    map[string]float64 `json:"disks,omitempty"`
+	// DiskUtil is the busy percent of each physical device.
+	DiskUtil map[string]float64 `json:"diskUtil,omitempty"`
+	// Sensors holds temperature and fan readings keyed by sensor ID.
+	Sensors map[string]float64 `json:"sensors,omitempty"`
+}
+
+type SystemdUnitStatus struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	LoadState   string `json:"loadState"`
+	ActiveState string `json:"activeState"`
+	SubState    string `json:"subState"`
+	// Restarts is NRestarts; nil on systemd versions that don't track it.
+	Restarts *int `json:"restarts,omitempty"`
+	MainPID  int  `json:"mainPid,omitempty"`
+	// Since is when the unit entered its current state (unix millis).
+	Since int64 `json:"since,omitempty"`
+}
+
+// CgroupStats describe the cgroup linksserver runs in when it is limited
+// or containerised; percentages are relative to the cgroup's own limits.
+type CgroupStats struct {
+	Version   int    `json:"version"`
+	Path      string `json:"path"`
+	Container bool   `json:"container"`
+	// CPUQuotaCores is quota/period; nil when unlimited.
+	CPUQuotaCores *float64 `json:"cpuQuotaCores,omitempty"`
+	// CPUPercent is usage relative to the quota, or to all host cores
+	// without one.
+	CPUPercent *float64 `json:"cpuPercent,omitempty"`
+	// CPUThrottledPercent is the share of scheduler periods that hit the
+	// quota since the last sample.
+	CPUThrottledPercent *float64       `json:"cpuThrottledPercent,omitempty"`
+	MemoryUsedBytes     uint64         `json:"memoryUsedBytes"`
+	MemoryLimitBytes    *uint64        `json:"memoryLimitBytes,omitempty"`
+	MemoryUsedPercent   *float64       `json:"memoryUsedPercent,omitempty"`
+	Pressure            *PressureStats `json:"pressure,omitempty"`
+}
+
+// PressureStats are Linux PSI figures (percent of wall time stalled).
+type PressureStats struct {
+	CPU    *PressureStat `json:"cpu,omitempty"`
+	Memory *PressureStat `json:"memory,omitempty"`
+	IO     *PressureStat `json:"io,omitempty"`
+}
+
+type PressureStat struct {
+	Some PressureAvg `json:"some"`
+	// Full is absent for CPU on older kernels.
+	Full *PressureAvg `json:"full,omitempty"`
+}
+
+type PressureAvg struct {
+	Avg10       float64 `json:"avg10"`
+	Avg60       float64 `json:"avg60"`
+	Avg300      float64 `json:"avg300"`
+	TotalMicros uint64  `json:"totalMicros"`
+}
+
+type PowerStats struct {
+	// ACOnline is nil when the host has no AC adapter in power_supply.
+	ACOnline  *bool          `json:"acOnline,omitempty"`
+	Batteries []BatteryStats `json:"batteries,omitempty"`
+	UPS       []UPSStats     `json:"ups,omitempty"`
+}
+
+type BatteryStats struct {
+	Name    string   `json:"name"`
+	Model   string   `json:"model,omitempty"`
+	Percent *float64 `json:"percent,omitempty"`
+	// Status is the kernel's: Charging, Discharging, Full, Not charging.
+	Status           string   `json:"status"`
+	TimeRemainingSec *int64   `json:"timeRemainingSec,omitempty"`
+	PowerWatts       *float64 `json:"powerWatts,omitempty"`
+	// HealthPercent is full capacity relative to design capacity.
+	HealthPercent *float64 `json:"healthPercent,omitempty"`
+}
+
+// UPSStats come from a NUT upsd.
+type UPSStats struct {
+	Name  string `json:"name"`
+	Model string `json:"model,omitempty"`
+	// Status is ups.status, e.g. "OL CHRG" or "OB LB".
+	Status        string   `json:"status"`
+	OnBattery     bool     `json:"onBattery"`
+	LowBattery    bool     `json:"lowBattery"`
+	ChargePercent *float64 `json:"chargePercent,omitempty"`
+	RuntimeSec    *int64   `json:"runtimeSec,omitempty"`
+	LoadPercent   *float64 `json:"loadPercent,omitempty"`
+	InputVoltage  *float64 `json:"inputVoltage,omitempty"`
+}
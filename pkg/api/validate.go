@@ -0,0 +1,151 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxTitleLength = 200
+	MaxURLLength   = 2048
+	MaxUnitLength  = 256
+)
+
+// AllowedURLSchemes are the schemes a link may use. Anything else, notably
+// javascript: and data:, is rejected.
+var AllowedURLSchemes = []string{"http", "https", "ftp", "sftp", "ssh", "smb"}
+
+// ValidationError is returned by NormalizeLink with every problem found.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "invalid link: " + strings.Join(msgs, "; ")
+}
+
+// NormalizeLink validates l and returns it in the form it is stored in:
+// trimmed fields, a normalised url (see NormalizeURL) and a full systemd
+// unit name. The error is a *ValidationError.
+func NormalizeLink(l Link) (Link, error) {
+	var errs []FieldError
+	fail := func(field, format string, args ...any) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	l.Title = strings.TrimSpace(l.Title)
+	switch {
+	case l.Title == "":
+		fail("title", "is required")
+	case utf8.RuneCountInString(l.Title) > MaxTitleLength:
+		fail("title", "must be at most %d characters", MaxTitleLength)
+	}
+
+	if u, err := NormalizeURL(l.Url); err != nil {
+		fail("url", "%s", err.Error())
+	} else {
+		l.Url = u
+	}
+
+	l.Unit = strings.TrimSpace(l.Unit)
+	if l.Unit != "" {
+		unit := NormalizeUnit(l.Unit)
+		switch {
+		case unit == "":
+			fail("unit", "is not a valid systemd unit name")
+		case len(unit) > MaxUnitLength:
+			fail("unit", "must be at most %d characters", MaxUnitLength)
+		default:
+			l.Unit = unit
+		}
+	}
+
+	if len(errs) > 0 {
+		return l, &ValidationError{Fields: errs}
+	}
+	return l, nil
+}
+
+// NormalizeURL checks the scheme against AllowedURLSchemes and returns the
+// url with a lower-case scheme and host, without the default port and
+// without a trailing slash, so "HTTP://Host:80/app/" and "http://host/app"
+// are the same link.
+func NormalizeURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("is required")
+	}
+	if len(raw) > MaxURLLength {
+		return "", fmt.Errorf("must be at most %d characters", MaxURLLength)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("is not a valid url")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme == "" {
+		return "", fmt.Errorf("must be absolute, e.g. http://%s", raw)
+	}
+	if !slices.Contains(AllowedURLSchemes, u.Scheme) {
+		return "", fmt.Errorf("scheme %q is not allowed, use one of %s", u.Scheme, strings.Join(AllowedURLSchemes, ", "))
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("must include a host")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if u.Scheme == "http" && port == "80" || u.Scheme == "https" && port == "443" {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+	return u.String(), nil
+}
+
+// URLKey is the url links are matched by: normalised when valid, as is
+// otherwise so links saved before validation can still be deleted.
+func URLKey(raw string) string {
+	if u, err := NormalizeURL(raw); err == nil {
+		return u
+	}
+	return raw
+}
+
+// NormalizeUnit appends ".service" to bare names like systemctl does and
+// returns "" for anything systemctl would refuse as a unit name, including
+// names starting with '-' that it would parse as options.
+func NormalizeUnit(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.HasPrefix(name, "-") {
+		return ""
+	}
+	for _, r := range name {
+		ok := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			strings.ContainsRune(":-_.\\@", r)
+		if !ok {
+			return ""
+		}
+	}
+	if !strings.Contains(name, ".") {
+		name += ".service"
+	}
+	return name
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{in: "http://grafana.lan", want: "http://grafana.lan"},
+		{in: "  HTTP://Grafana.LAN:80/  ", want: "http://grafana.lan"},
+		{in: "https://nas.lan:443/", want: "https://nas.lan"},
+		{in: "https://nas.lan:80/", want: "https://nas.lan:80"},
+		{in: "http://nas.lan:8080/app///", want: "http://nas.lan:8080/app"},
+		{in: "http://nas.lan/App/?q=1#top", want: "http://nas.lan/App?q=1#top"},
+		{in: "http://[FE80::1]:80/", want: "http://[fe80::1]"},
+		{in: "http://[::1]:8080", want: "http://[::1]:8080"},
+		{in: "ssh://User@Host.lan:22", want: "ssh://User@host.lan:22"},
+		{in: "SMB://fileserver/share/", want: "smb://fileserver/share"},
+
+		{in: "", wantErr: "is required"},
+		{in: "   ", wantErr: "is required"},
+		// No scheme defaulting: a bare host is rejected with a hint.
+		{in: "grafana.lan", wantErr: "must be absolute, e.g. http://grafana.lan"},
+		{in: "/relative/path", wantErr: "must be absolute"},
+		{in: "javascript:alert(1)", wantErr: `scheme "javascript" is not allowed`},
+		{in: "data:text/html,hi", wantErr: `scheme "data" is not allowed`},
+		{in: "file:///etc/passwd", wantErr: `scheme "file" is not allowed`},
+		{in: "http://", wantErr: "must include a host"},
+		{in: "http:///path", wantErr: "must include a host"},
+		{in: "http://a b.lan/%zz", wantErr: "is not a valid url"},
+		{in: "http://x.lan/" + strings.Repeat("a", MaxURLLength), wantErr: "must be at most 2048 characters"},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeURL(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NormalizeURL(%q) = %q, %v; want error %q", tt.in, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeURL(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestURLKey(t *testing.T) {
+	if URLKey("HTTP://Host.lan:80/") != URLKey("http://host.lan") {
+		t.Error("equivalent urls have different keys")
+	}
+	// Links saved before validation keep their url as the key.
+	if got := URLKey("grafana.lan/"); got != "grafana.lan/" {
+		t.Errorf("URLKey of an invalid url = %q, want it unchanged", got)
+	}
+}
+
+func TestNormalizeLink(t *testing.T) {
+	got, err := NormalizeLink(Link{Title: "  Grafana ", Url: "HTTP://Grafana.lan/", Unit: " grafana-server "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Link{Title: "Grafana", Url: "http://grafana.lan", Unit: "grafana-server.service"}); got != want {
+		t.Errorf("NormalizeLink = %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		name   string
+		link   Link
+		fields []string
+	}{
+		{"empty", Link{}, []string{"title", "url"}},
+		{"long title", Link{Title: strings.Repeat("é", MaxTitleLength+1), Url: "http://a.lan"}, []string{"title"}},
+		{"bad scheme", Link{Title: "x", Url: "javascript:void(0)"}, []string{"url"}},
+		{"bad unit", Link{Title: "x", Url: "http://a.lan", Unit: "nginx; reboot"}, []string{"unit"}},
+		{"option as unit", Link{Title: "x", Url: "http://a.lan", Unit: "-H"}, []string{"unit"}},
+		{"long unit", Link{Title: "x", Url: "http://a.lan", Unit: strings.Repeat("a", MaxUnitLength) + ".service"}, []string{"unit"}},
+	}
+	for _, tt := range tests {
+		_, err := NormalizeLink(tt.link)
+		var invalid *ValidationError
+		if !errors.As(err, &invalid) {
+			t.Errorf("%s: err = %v, want a *ValidationError", tt.name, err)
+			continue
+		}
+		var fields []string
+		for _, f := range invalid.Fields {
+			fields = append(fields, f.Field)
+		}
+		if strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+			t.Errorf("%s: fields = %v, want %v", tt.name, fields, tt.fields)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Package client talks to a linksserver over its HTTP API: it manages links
+// and reads resource snapshots, processes and agents. The request and
+// response types come from pkg/api, which the server encodes too, so they
+// stay in step with /api/openapi.json.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tomek7667/links/pkg/api"
+)
+
+const defaultTimeout = 10 * time.Second
+
+type (
+	Link              = api.Link
+	ResourcesSnapshot = api.ResourcesSnapshot
+	ProcessList       = api.ProcessList
+	ProcessInfo       = api.ProcessInfo
+	AgentStatus       = api.AgentStatus
+	IngestBatch       = api.IngestBatch
+	VersionInfo       = api.VersionInfo
+	FieldError        = api.FieldError
+	ValidationError   = api.ValidationError
+)
+
+// ProcessQuery selects rows of the process table. Zero values mean the
+// server defaults: sorted by CPU, descending, no limit.
+type ProcessQuery struct {
+	Sort   string
+	Asc    bool
+	Limit  int
+	Filter string
+	User   string
+	State  string
+}
+
+type Options struct {
+	// BaseURL is where the server is reachable, including any --base-path,
+	// e.g. "https://intranet/links".
+	BaseURL string
+	// IngestToken is only needed for Ingest.
+	IngestToken string
+	// HTTPClient defaults to a client with a 10s timeout.
+	HTTPClient *http.Client
+}
+
+type Client struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+// Error is returned when the server answers with a non-2xx status. Code is
+// the machine-readable code from the error body, e.g. "validation_failed"
+// or "rate_limited"; it is empty when the body wasn't a JSON error.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Fields     []FieldError
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("linksserver responded %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("linksserver responded %d: %s", e.StatusCode, e.Message)
+}
+
+func New(opts Options) (*Client, error) {
+	base := strings.TrimRight(strings.TrimSpace(opts.BaseURL), "/")
+	if base == "" {
+		return nil, fmt.Errorf("base url is required")
+	}
+	if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		return nil, fmt.Errorf("base url %q must start with http:// or https://", opts.BaseURL)
+	}
+	hc := opts.HTTPClient
+	if hc == nil {
+		hc = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Client{base: base, token: opts.IngestToken, http: hc}, nil
+}
+
+func (c *Client) Links(ctx context.Context) ([]Link, error) {
+	var links []Link
+	err := c.do(ctx, http.MethodGet, "/api/links", nil, &links)
+	return links, err
+}
+
+// SaveLink adds a link or replaces the one with the same URL. Invalid links
+// fail with a *ValidationError without a request, using the same rules as
+// the server.
+func (c *Client) SaveLink(ctx context.Context, link Link) error {
+	link, err := api.NormalizeLink(link)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, "/api/links", link, nil)
+}
+
+func (c *Client) DeleteLink(ctx context.Context, linkURL string) error {
+	body := struct {
+		Url string `json:"url"`
+	}{Url: linkURL}
+	return c.do(ctx, http.MethodDelete, "/api/links", body, nil)
+}
+
+// Resources returns the current snapshot, with graph history if asked for.
+func (c *Client) Resources(ctx context.Context, withHistory bool) (*ResourcesSnapshot, error) {
+	path := "/api/resources"
+	if withHistory {
+		path += "?history=1"
+	}
+	var snap ResourcesSnapshot
+	if err := c.do(ctx, http.MethodGet, path, nil, &snap); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+func (c *Client) Processes(ctx context.Context, q ProcessQuery, withHistory bool) (*ProcessList, error) {
+	qs := url.Values{}
+	if q.Sort != "" {
+		qs.Set("sort", q.Sort)
+	}
+	if q.Asc {
+		qs.Set("order", "asc")
+	}
+	if q.Limit > 0 {
+		qs.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Filter != "" {
+		qs.Set("q", q.Filter)
+	}
+	if q.User != "" {
+		qs.Set("user", q.User)
+	}
+	if q.State != "" {
+		qs.Set("state", q.State)
+	}
+	if withHistory {
+		qs.Set("history", "1")
+	}
+	path := "/api/processes"
+	if len(qs) > 0 {
+		path += "?" + qs.Encode()
+	}
+	var list ProcessList
+	if err := c.do(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (c *Client) Agents(ctx context.Context) ([]AgentStatus, error) {
+	var agents []AgentStatus
+	err := c.do(ctx, http.MethodGet, "/api/agents", nil, &agents)
+	return agents, err
+}
+
+// Ingest pushes snapshots the way `linksserver agent` does; it needs
+// Options.IngestToken.
+func (c *Client) Ingest(ctx context.Context, batch IngestBatch) error {
+	if c.token == "" {
+		return fmt.Errorf("ingest token is required")
+	}
+	return c.do(ctx, http.MethodPost, "/api/ingest", batch, nil)
+}
+
+// Version returns the server's build information and uptime.
+func (c *Client) Version(ctx context.Context) (*VersionInfo, error) {
+	var info VersionInfo
+	if err := c.do(ctx, http.MethodGet, "/api/version", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// OpenAPI returns the server's OpenAPI document as raw JSON.
+func (c *Client) OpenAPI(ctx context.Context) (json.RawMessage, error) {
+	var doc json.RawMessage
+	err := c.do(ctx, http.MethodGet, "/api/openapi.json", nil, &doc)
+	return doc, err
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+		var body api.ErrorResponse
+		if err := json.Unmarshal(msg, &body); err == nil && body.Error.Code != "" {
+			return &Error{
+				StatusCode: res.StatusCode,
+				Code:       body.Error.Code,
+				Message:    body.Error.Message,
+				Fields:     body.Error.Fields,
+			}
+		}
+		// Proxies in front of the server answer in their own format.
+		return &Error{StatusCode: res.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}